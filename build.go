@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// needsBuildContext returns whether the Build helper of a root table counts
+// what its Insert drops in a BuildContext, the duplicate keys of every table
+// but a multimap one.
+func (g *Generator) needsBuildContext(msg *descriptor.DescriptorProto) bool {
+	if _, haveKeyFiled := g.hashEntryMessages[msg]; !haveKeyFiled {
+		return false
+	}
+	return g.messageOptions(msg).Table != TableMulti
+}
+
+// DumpBuildContextTypes emits mmdata::pb::BuildContext if the Build helper of
+// a root table of the file needs it.
+func (g *Generator) DumpBuildContextTypes(file *descriptor.FileDescriptorProto) {
+	for _, msg := range file.MessageType {
+		if g.needsBuildContext(msg) {
+			fmt.Fprintf(&g.OutputBuffer, "%s\n", buildContextTypesCode)
+			return
+		}
+	}
+}
+
+// buildContextTypesCode holds mmdata::pb::BuildContext, the counters of the
+// Build of a root table created by its helper. The builder creates the table
+// itself, so its Insert finds the context of the build running in the thread
+// with Current().
+const buildContextTypesCode = `#ifndef MMDATA_BUILD_CONTEXT_
+#define MMDATA_BUILD_CONTEXT_
+namespace mmdata
+{
+    namespace pb
+    {
+        struct PerfectHashPlan;
+        struct BuildContext
+        {
+            uint64_t duplicate_keys;
+            uint64_t unique_index_conflicts;
+            //the hash of a PERFECT_HASH table, computed between its two builds
+            PerfectHashPlan* perfect_hash;
+            BuildContext* previous;
+
+            BuildContext():duplicate_keys(0),unique_index_conflicts(0),perfect_hash(NULL),previous(Current())
+            {
+                Current() = this;
+            }
+            ~BuildContext()
+            {
+                Current() = previous;
+            }
+            static BuildContext*& Current()
+            {
+                static thread_local BuildContext* current = NULL;
+                return current;
+            }
+            static void CountDuplicateKey()
+            {
+                if(NULL != Current()) Current()->duplicate_keys++;
+            }
+            static void CountUniqueIndexConflict()
+            {
+                if(NULL != Current()) Current()->unique_index_conflicts++;
+            }
+
+        private:
+            BuildContext(const BuildContext&);
+            BuildContext& operator=(const BuildContext&);
+        };
+    }
+}
+#endif /* MMDATA_BUILD_CONTEXT_ */
+`
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// isBytesField returns whether a field holds bytes with the generated bytes
+// types, the value of a BytesValue included.
+func (g *Generator) isBytesField(field *descriptor.FieldDescriptorProto) bool {
+	return g.valueField(field).GetType() == descriptor.FieldDescriptorProto_TYPE_BYTES && len(g.fieldOptions(field).CppType) == 0
+}
+
+func (g *Generator) isFixedBytesField(field *descriptor.FieldDescriptorProto) bool {
+	return g.isFixedField(field) && field.GetType() == descriptor.FieldDescriptorProto_TYPE_BYTES && field.GetLabel() != descriptor.FieldDescriptorProto_LABEL_REPEATED
+}
+
+// DumpBytesTypes emits the bytes types if the file uses them, after the
+// fixed-size types as FixedBytes is a FixedString.
+func (g *Generator) DumpBytesTypes(file *descriptor.FileDescriptorProto) {
+	if fileHasField(file, g.isBytesField) {
+		fmt.Fprintf(&g.OutputBuffer, "%s\n", bytesTypesCode)
+	}
+	if fileHasField(file, g.isFixedBytesField) {
+		fmt.Fprintf(&g.OutputBuffer, "%s\n", fixedBytesTypesCode)
+	}
+}
+
+// bytesTypesCode holds mmdata::pb::Bytes, a SHMString of its own type so that
+// bytes are printed as hex and mapped to base64 in json, and hashed as bytes
+// when used as keys.
+const bytesTypesCode = `#ifndef MMDATA_BYTES_TYPES_
+#define MMDATA_BYTES_TYPES_
+#include <ostream>
+#include <string>
+namespace mmdata
+{
+    namespace pb
+    {
+        struct Bytes : public mmdata::SHMString
+        {
+            Bytes(const mmdata::CharAllocator& alloc):mmdata::SHMString(alloc)
+            {}
+            Bytes(const char* s, const mmdata::CharAllocator& alloc):mmdata::SHMString(s, alloc)
+            {}
+            Bytes(const char* s, size_t n, const mmdata::CharAllocator& alloc):mmdata::SHMString(s, n, alloc)
+            {}
+            Bytes& operator=(const mmdata::SHMString& s)
+            {
+                mmdata::SHMString::operator=(s);
+                return *this;
+            }
+        };
+
+        inline std::ostream& PrintHex(std::ostream& os, const char* data, size_t n)
+        {
+            static const char digits[] = "0123456789abcdef";
+            for(size_t i = 0; i < n; i++)
+            {
+                uint8_t c = static_cast<uint8_t>(data[i]);
+                os<<digits[c >> 4]<<digits[c & 0xf];
+            }
+            return os;
+        }
+        inline std::string EncodeBase64(const char* data, size_t n)
+        {
+            static const char table[] = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/";
+            std::string s;
+            for(size_t i = 0; i < n; i += 3)
+            {
+                uint32_t v = static_cast<uint32_t>(static_cast<uint8_t>(data[i])) << 16;
+                if(i + 1 < n) v |= static_cast<uint32_t>(static_cast<uint8_t>(data[i + 1])) << 8;
+                if(i + 2 < n) v |= static_cast<uint8_t>(data[i + 2]);
+                s.push_back(table[(v >> 18) & 0x3f]);
+                s.push_back(table[(v >> 12) & 0x3f]);
+                s.push_back(i + 1 < n ? table[(v >> 6) & 0x3f] : '=');
+                s.push_back(i + 2 < n ? table[v & 0x3f] : '=');
+            }
+            return s;
+        }
+        //decodes the standard or the url-safe alphabet, with optional padding
+        inline bool DecodeBase64(const std::string& s, std::string& data)
+        {
+            data.clear();
+            uint32_t bits = 0;
+            int count = 0;
+            size_t i = 0;
+            for(; i < s.size() && s[i] != '='; i++)
+            {
+                char c = s[i];
+                uint32_t v;
+                if(c >= 'A' && c <= 'Z') v = c - 'A';
+                else if(c >= 'a' && c <= 'z') v = c - 'a' + 26;
+                else if(c >= '0' && c <= '9') v = c - '0' + 52;
+                else if(c == '+' || c == '-') v = 62;
+                else if(c == '/' || c == '_') v = 63;
+                else return false;
+                bits = (bits << 6) | v;
+                count += 6;
+                if(count >= 8)
+                {
+                    count -= 8;
+                    data.push_back(static_cast<char>((bits >> count) & 0xff));
+                }
+            }
+            //a single char left over is not a byte
+            if(count >= 6) return false;
+            for(; i < s.size(); i++)
+            {
+                if(s[i] != '=') return false;
+            }
+            return true;
+        }
+
+        inline std::size_t hash_value(const Bytes& v) { return boost::hash_range(v.data(), v.data() + v.size()); }
+        inline std::ostream& operator<<(std::ostream& os, const Bytes& v)
+        {
+            return PrintHex(os, v.data(), v.size());
+        }
+    }
+}
+namespace kcfg
+{
+    inline bool Parse(const rapidjson::Value& json, const char* name, mmdata::pb::Bytes& v)
+    {
+        std::string s, data;
+        if(!Parse(json, name, s) || !mmdata::pb::DecodeBase64(s, data)) return false;
+        v.assign(data.data(), data.size());
+        return true;
+    }
+    inline void Serialize(rapidjson::Value& json, rapidjson::Value::AllocatorType& allocator, const char* name, const mmdata::pb::Bytes& v)
+    {
+        Serialize(json, allocator, name, mmdata::pb::EncodeBase64(v.data(), v.size()));
+    }
+}
+#endif /* MMDATA_BYTES_TYPES_ */
+`
+
+// fixedBytesTypesCode holds mmdata::pb::FixedBytes, the FixedString of bytes
+// fields with (mmdata.fixed_size).
+const fixedBytesTypesCode = `#ifndef MMDATA_FIXED_BYTES_TYPES_
+#define MMDATA_FIXED_BYTES_TYPES_
+namespace mmdata
+{
+    namespace pb
+    {
+        template<uint32_t N, bool Truncate = false>
+        struct FixedBytes : public FixedString<N, Truncate>
+        {
+            FixedBytes()
+            {}
+            FixedBytes(const char* s):FixedString<N, Truncate>(s)
+            {}
+            FixedBytes(const char* s, size_t n):FixedString<N, Truncate>(s, n)
+            {}
+        };
+        template<uint32_t N, bool Truncate>
+        inline std::ostream& operator<<(std::ostream& os, const FixedBytes<N, Truncate>& v)
+        {
+            return PrintHex(os, v.data(), v.size());
+        }
+    }
+}
+namespace kcfg
+{
+    template<uint32_t N, bool Truncate>
+    inline bool Parse(const rapidjson::Value& json, const char* name, mmdata::pb::FixedBytes<N, Truncate>& v)
+    {
+        std::string s, data;
+        if(!Parse(json, name, s) || !mmdata::pb::DecodeBase64(s, data)) return false;
+        return v.assign(data);
+    }
+    template<uint32_t N, bool Truncate>
+    inline void Serialize(rapidjson::Value& json, rapidjson::Value::AllocatorType& allocator, const char* name, const mmdata::pb::FixedBytes<N, Truncate>& v)
+    {
+        Serialize(json, allocator, name, mmdata::pb::EncodeBase64(v.data(), v.size()));
+    }
+}
+#endif /* MMDATA_FIXED_BYTES_TYPES_ */
+`
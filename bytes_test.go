@@ -0,0 +1,35 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+//go:generate protoc -Itestdata -I. --include_imports --include_source_info --descriptor_set_out=testdata/bytes.pb testdata/bytes.proto
+
+func TestBytes(t *testing.T) {
+	header := testGenerate(t, "bytes", "")["bytes.proto.hpp"]
+	tests := []struct {
+		field string
+		want  string
+	}{
+		{"raw", "mmdata::pb::Bytes raw;\n            mmdata::SHMString name;\n"},
+		{"chunks", "mmdata::SHMVector<mmdata::pb::Bytes>::Type chunks;"},
+		{"blobs", "mmdata::SHMMap<mmdata::SHMString, mmdata::pb::Bytes>::Type blobs;"},
+		{"wrapped", "mmdata::pb::Bytes wrapped;"},
+		{"tag", "mmdata::pb::FixedBytes<4> tag;"},
+		{"ob", "typedef Item_OOneof<mmdata::pb::Bytes, int32_t> OOneof;"},
+		//defaults may hold NUL bytes
+		{"raw", "raw(\"a\\000\\377\", 3, alloc)"},
+		{"raw", "if(v.has_raw()) hash ^= hash_value(v.raw);"},
+		{"Entry.k", "typedef mmdata::pb::Bytes key_type;"},
+		{"KeyEntry.v", "typedef mmdata::pb::Bytes value_type;"},
+		{"Bytes", "inline bool DecodeBase64(const std::string& s, std::string& data)"},
+		{"FixedBytes", "struct FixedBytes : public FixedString<N, Truncate>"},
+	}
+	for _, test := range tests {
+		if !strings.Contains(header, test.want) {
+			t.Errorf("bytes.proto.hpp does not contain %q for %s:\n%s", test.want, test.field, header)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// values of the paths parameter
+const (
+	pathsImport         = "import"
+	pathsSourceRelative = "source_relative"
+	pathsFlat           = "flat"
+)
+
+// values of the nested parameter
+const (
+	nestedStruct = "struct"
+	nestedMangle = "mangle"
+)
+
+// Config is the generator configuration given by the plugin parameter, a
+// comma-separated list of key=value, like
+//
+//	--mmdata_opt=paths=source_relative,header_ext=.h,emit_cpp=false
+type Config struct {
+	//extension appended to the proto file name for the generated header/source
+	HeaderExt string
+	SourceExt string
+	//prefix of the kcfg/mmdata includes, like "mmdata/" for "mmdata/mmdata.hpp"
+	IncludePrefix string
+	//extra namespace put around the proto package namespaces
+	NamespacePrefix string
+	//whether to emit the .cpp with the build/test helpers
+	EmitCpp bool
+	//whether to emit proto enums as enum class instead of plain enum
+	EnumClass bool
+	//nested messages as nested structs, or mangled like Outer_Inner
+	Nested string
+	//output path layout, import, source_relative or flat
+	Paths string
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		HeaderExt: ".hpp",
+		SourceExt: ".cpp",
+		EmitCpp:   true,
+		EnumClass: true,
+		Nested:    nestedStruct,
+		Paths:     pathsImport,
+	}
+}
+
+func ParseConfig(param string) (*Config, error) {
+	c := DefaultConfig()
+	for _, p := range strings.Split(param, ",") {
+		p = strings.TrimSpace(p)
+		if len(p) == 0 {
+			continue
+		}
+		k, v := p, ""
+		if idx := strings.Index(p, "="); idx >= 0 {
+			k, v = p[:idx], p[idx+1:]
+		}
+		switch k {
+		case "header_ext":
+			c.HeaderExt = v
+		case "source_ext":
+			c.SourceExt = v
+		case "include_prefix":
+			c.IncludePrefix = v
+			if len(v) > 0 && !strings.HasSuffix(v, "/") {
+				c.IncludePrefix = v + "/"
+			}
+		case "namespace_prefix":
+			c.NamespacePrefix = strings.Replace(v, "::", ".", -1)
+		case "emit_cpp":
+			b, err := parseBoolParam(k, v)
+			if err != nil {
+				return nil, err
+			}
+			c.EmitCpp = b
+		case "enum_class":
+			b, err := parseBoolParam(k, v)
+			if err != nil {
+				return nil, err
+			}
+			c.EnumClass = b
+		case "nested":
+			switch v {
+			case nestedStruct, nestedMangle:
+				c.Nested = v
+			default:
+				return nil, fmt.Errorf("invalid value for parameter nested:%s", v)
+			}
+		case "paths":
+			switch v {
+			case pathsImport, pathsSourceRelative, pathsFlat:
+				c.Paths = v
+			default:
+				return nil, fmt.Errorf("invalid value for parameter paths:%s", v)
+			}
+		default:
+			return nil, fmt.Errorf("unknown parameter:%s", k)
+		}
+	}
+	if len(c.HeaderExt) == 0 {
+		return nil, fmt.Errorf("empty value for parameter header_ext")
+	}
+	if c.EmitCpp && len(c.SourceExt) == 0 {
+		return nil, fmt.Errorf("empty value for parameter source_ext")
+	}
+	return c, nil
+}
+
+func parseBoolParam(k, v string) (bool, error) {
+	switch v {
+	case "", "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid value for parameter %s:%s", k, v)
+}
+
+// Namespaces returns the C++ namespaces of a proto package, including the
+// configured namespace prefix.
+func (c *Config) Namespaces(pkg string) []string {
+	var ss []string
+	for _, name := range []string{c.NamespacePrefix, pkg} {
+		for _, ns := range strings.Split(name, ".") {
+			if len(ns) > 0 {
+				ss = append(ss, ns)
+			}
+		}
+	}
+	return ss
+}
+
+// OutputName returns the generated file name of a proto file without
+// extension. import puts the file in the directory of its import path, the
+// go_package option up to any ';' like protoc-gen-go does, or else in the
+// directory of the proto file. source_relative always keeps the directory of
+// the proto file and flat strips it.
+func (c *Config) OutputName(file *descriptor.FileDescriptorProto) string {
+	pbfile := file.GetName()
+	switch c.Paths {
+	case pathsFlat:
+		return path.Base(pbfile)
+	case pathsSourceRelative:
+		return pbfile
+	}
+	importPath := file.GetOptions().GetGoPackage()
+	if idx := strings.Index(importPath, ";"); idx >= 0 {
+		importPath = importPath[:idx]
+	}
+	if len(importPath) == 0 {
+		return pbfile
+	}
+	return path.Join(importPath, path.Base(pbfile))
+}
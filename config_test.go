@@ -0,0 +1,106 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+func TestParseConfig(t *testing.T) {
+	tests := []struct {
+		param string
+		want  func(c *Config)
+	}{
+		{"", func(c *Config) {}},
+		{" , ", func(c *Config) {}},
+		{"header_ext=.h,source_ext=.cc", func(c *Config) { c.HeaderExt, c.SourceExt = ".h", ".cc" }},
+		{"include_prefix=mmdata", func(c *Config) { c.IncludePrefix = "mmdata/" }},
+		{"include_prefix=mmdata/", func(c *Config) { c.IncludePrefix = "mmdata/" }},
+		{"namespace_prefix=shm::data", func(c *Config) { c.NamespacePrefix = "shm.data" }},
+		{"emit_cpp=false,source_ext=", func(c *Config) { c.EmitCpp, c.SourceExt = false, "" }},
+		{"emit_cpp", func(c *Config) {}},
+		{"enum_class=false", func(c *Config) { c.EnumClass = false }},
+		{"nested=mangle", func(c *Config) { c.Nested = nestedMangle }},
+		{"paths=source_relative", func(c *Config) { c.Paths = pathsSourceRelative }},
+		{"paths=flat", func(c *Config) { c.Paths = pathsFlat }},
+	}
+	for _, test := range tests {
+		got, err := ParseConfig(test.param)
+		if err != nil {
+			t.Errorf("ParseConfig(%q): %v", test.param, err)
+			continue
+		}
+		want := DefaultConfig()
+		test.want(want)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("ParseConfig(%q) = %+v, want %+v", test.param, got, want)
+		}
+	}
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	tests := []struct {
+		param string
+		err   string
+	}{
+		{"foo=bar", "unknown parameter:foo"},
+		{"emit_cpp=no", "invalid value for parameter emit_cpp:no"},
+		{"enum_class=1", "invalid value for parameter enum_class:1"},
+		{"nested=flat", "invalid value for parameter nested:flat"},
+		{"paths=relative", "invalid value for parameter paths:relative"},
+		{"header_ext=", "empty value for parameter header_ext"},
+		{"source_ext=", "empty value for parameter source_ext"},
+	}
+	for _, test := range tests {
+		_, err := ParseConfig(test.param)
+		if nil == err || err.Error() != test.err {
+			t.Errorf("ParseConfig(%q) error = %v, want %q", test.param, err, test.err)
+		}
+	}
+}
+
+func TestConfigNamespaces(t *testing.T) {
+	tests := []struct {
+		prefix, pkg string
+		want        []string
+	}{
+		{"", "", nil},
+		{"", "a.b", []string{"a", "b"}},
+		{"shm.data", "a", []string{"shm", "data", "a"}},
+		{"shm", "", []string{"shm"}},
+	}
+	for _, test := range tests {
+		c := &Config{NamespacePrefix: test.prefix}
+		if got := c.Namespaces(test.pkg); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Namespaces(%q) with prefix %q = %v, want %v", test.pkg, test.prefix, got, test.want)
+		}
+	}
+}
+
+func TestConfigOutputName(t *testing.T) {
+	tests := []struct {
+		paths, name, goPackage string
+		want                   string
+	}{
+		{pathsFlat, "data.proto", "x/y", "data.proto"},
+		{pathsFlat, "a/b/data.proto", "x/y", "data.proto"},
+		{pathsSourceRelative, "data.proto", "x/y", "data.proto"},
+		{pathsSourceRelative, "a/b/data.proto", "x/y", "a/b/data.proto"},
+		{pathsImport, "a/b/data.proto", "x/y;y", "x/y/data.proto"},
+		{pathsImport, "a/b/data.proto", "example.com/x/y", "example.com/x/y/data.proto"},
+		{pathsImport, "a/b/data.proto", ";y", "a/b/data.proto"},
+		{pathsImport, "a/b/data.proto", "", "a/b/data.proto"},
+	}
+	for _, test := range tests {
+		c := &Config{Paths: test.paths}
+		file := &descriptor.FileDescriptorProto{Name: proto.String(test.name)}
+		if len(test.goPackage) > 0 {
+			file.Options = &descriptor.FileOptions{GoPackage: proto.String(test.goPackage)}
+		}
+		if got := c.OutputName(file); got != test.want {
+			t.Errorf("OutputName(%q) with paths=%s and go_package %q = %q, want %q", test.name, test.paths, test.goPackage, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// compileCpp builds testdata/cpp/name.cpp as dir/name, with the headers in
+// dir, testdata/cpp and includes, using $CXX or c++. The test is skipped
+// without a compiler.
+func compileCpp(t *testing.T, dir string, name string, includes ...string) string {
+	cxx := os.Getenv("CXX")
+	if len(cxx) == 0 {
+		cxx = "c++"
+	}
+	if _, err := exec.LookPath(cxx); err != nil {
+		t.Skipf("no C++ compiler:%v", err)
+	}
+	exe := filepath.Join(dir, name)
+	args := []string{"-std=c++11", "-I", dir, "-I", filepath.Join("testdata", "cpp")}
+	for _, include := range includes {
+		args = append(args, "-I", include)
+	}
+	args = append(args, "-o", exe, filepath.Join("testdata", "cpp", name+".cpp"))
+	args = append(args, strings.Fields(os.Getenv("MMDATA_LDFLAGS"))...)
+	if out, err := exec.Command(cxx, args...).CombinedOutput(); err != nil {
+		t.Fatalf("compiling %s.cpp:%v\n%s", name, err, out)
+	}
+	return exe
+}
+
+// compileGenerated generates testdata/name.proto and builds the test program
+// testdata/cpp/name.cpp with it against mmdata, kcfg, rapidjson and boost,
+// found in the directories of $MMDATA_INCLUDE, a list like $PATH, and linked
+// with $MMDATA_LDFLAGS. The test is skipped without $MMDATA_INCLUDE.
+func compileGenerated(t *testing.T, name string) string {
+	includes := filepath.SplitList(os.Getenv("MMDATA_INCLUDE"))
+	if len(includes) == 0 {
+		t.Skip("MMDATA_INCLUDE is not set")
+	}
+	dir := t.TempDir()
+	for file, content := range testGenerate(t, name, "") {
+		if err := ioutil.WriteFile(filepath.Join(dir, file), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return compileCpp(t, dir, name, includes...)
+}
+
+// runCpp runs a program built by compileCpp with the given lines on stdin,
+// and returns the lines it printed.
+func runCpp(t *testing.T, exe string, lines []string) []string {
+	cmd := exec.Command(exe)
+	cmd.Stdin = strings.NewReader(strings.Join(lines, "\n") + "\n")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("running %s:%v\n%s%s", filepath.Base(exe), err, out, stderr.String())
+	}
+	return strings.Split(strings.TrimSuffix(string(out), "\n"), "\n")
+}
+
+// testCppChecks runs the checks of testdata/cpp/name.cpp on the code generated
+// for testdata/name.proto.
+func testCppChecks(t *testing.T, name string) {
+	exe := compileGenerated(t, name)
+	if out := runCpp(t, exe, nil); len(out) != 1 || out[0] != "ok" {
+		t.Errorf("%s printed:\n%s", name, strings.Join(out, "\n"))
+	}
+}
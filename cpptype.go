@@ -0,0 +1,149 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+type scalarKind int
+
+const (
+	scalarNone scalarKind = iota
+	scalarInt
+	scalarFloat
+	scalarBool
+)
+
+// builtinType is a C++ type (mmdata.cpp_type) checks against the proto type.
+type builtinType struct {
+	kind   scalarKind
+	bits   int
+	signed bool
+}
+
+var builtinTypes = map[string]builtinType{
+	"int8_t":   {kind: scalarInt, bits: 8, signed: true},
+	"int16_t":  {kind: scalarInt, bits: 16, signed: true},
+	"int32_t":  {kind: scalarInt, bits: 32, signed: true},
+	"int64_t":  {kind: scalarInt, bits: 64, signed: true},
+	"uint8_t":  {kind: scalarInt, bits: 8},
+	"uint16_t": {kind: scalarInt, bits: 16},
+	"uint32_t": {kind: scalarInt, bits: 32},
+	"uint64_t": {kind: scalarInt, bits: 64},
+	"float":    {kind: scalarFloat},
+	"double":   {kind: scalarFloat},
+	"bool":     {kind: scalarBool},
+}
+
+// cppTypeNamePattern matches a qualified name with optional template
+// arguments, like "::ns::Code<8>".
+var cppTypeNamePattern = regexp.MustCompile(`^(::)?[A-Za-z_]\w*(::[A-Za-z_]\w*)*(<[\w:<>, ]*>)?$`)
+
+func protoTypeName(t descriptor.FieldDescriptorProto_Type) string {
+	return strings.ToLower(strings.TrimPrefix(t.String(), "TYPE_"))
+}
+
+func protoScalarKind(t descriptor.FieldDescriptorProto_Type) scalarKind {
+	switch t {
+	case descriptor.FieldDescriptorProto_TYPE_DOUBLE, descriptor.FieldDescriptorProto_TYPE_FLOAT:
+		return scalarFloat
+	case descriptor.FieldDescriptorProto_TYPE_BOOL:
+		return scalarBool
+	case descriptor.FieldDescriptorProto_TYPE_STRING, descriptor.FieldDescriptorProto_TYPE_BYTES,
+		descriptor.FieldDescriptorProto_TYPE_ENUM, descriptor.FieldDescriptorProto_TYPE_MESSAGE, descriptor.FieldDescriptorProto_TYPE_GROUP:
+		return scalarNone
+	}
+	return scalarInt
+}
+
+// isCustomType returns whether a field has a (mmdata.cpp_type) which is not a
+// builtin type, the type then provides the hash_value, operators and kcfg
+// mapping of the field.
+func (g *Generator) isCustomType(field *descriptor.FieldDescriptorProto) bool {
+	t := g.fieldOptions(field).CppType
+	if len(t) == 0 {
+		return false
+	}
+	_, builtin := builtinTypes[t]
+	return !builtin
+}
+
+// isCharType returns whether a field is stored as an 8-bit integer, which
+// std::ostream prints as a character.
+func (g *Generator) isCharType(field *descriptor.FieldDescriptorProto) bool {
+	t := g.fieldOptions(field).CppType
+	return t == "int8_t" || t == "uint8_t"
+}
+
+// verifyCppTypes reports the invalid (mmdata.cpp_type) of the fields of a
+// message and its nested messages.
+func (g *Generator) verifyCppTypes(msg *descriptor.DescriptorProto) {
+	for _, nest := range msg.NestedType {
+		g.verifyCppTypes(nest)
+	}
+	for _, field := range msg.Field {
+		opts := g.fieldOptions(field)
+		if len(opts.CppType) == 0 {
+			continue
+		}
+		if g.isMapField(field) {
+			g.fieldError(field, "Option (mmdata.cpp_type) is not supported for map fields")
+			continue
+		}
+		t, builtin := builtinTypes[opts.CppType]
+		if !builtin {
+			if !cppTypeNamePattern.MatchString(opts.CppType) {
+				g.fieldError(field, "Invalid option (mmdata.cpp_type):%q is not a C++ type name", opts.CppType)
+			}
+			continue
+		}
+		if opts.CppTypeAlloc {
+			g.fieldError(field, "Option (mmdata.cpp_type) %s is not constructed with an allocator", opts.CppType)
+			continue
+		}
+		protoType := g.valueField(field).GetType()
+		if protoScalarKind(protoType) != t.kind {
+			g.fieldError(field, "Option (mmdata.cpp_type) %s is not valid for %s fields", opts.CppType, protoTypeName(protoType))
+			continue
+		}
+		if text, exist := g.defaultText(field); exist && t.kind == scalarInt && !fitsInt(text, t) {
+			g.fieldError(field, "Default %s overflows (mmdata.cpp_type) %s", text, opts.CppType)
+		}
+	}
+}
+
+func fitsInt(text string, t builtinType) bool {
+	if t.signed {
+		_, err := strconv.ParseInt(text, 0, t.bits)
+		return err == nil
+	}
+	_, err := strconv.ParseUint(text, 0, t.bits)
+	return err == nil
+}
+
+// cppTypeIncludes returns the headers of the (mmdata.cpp_type) of the fields
+// of a file, without duplicates.
+func (g *Generator) cppTypeIncludes(file *descriptor.FileDescriptorProto) []string {
+	var includes []string
+	seen := make(map[string]bool)
+	var walk func(msg *descriptor.DescriptorProto)
+	walk = func(msg *descriptor.DescriptorProto) {
+		for _, field := range msg.Field {
+			include := g.fieldOptions(field).CppTypeInclude
+			if len(include) > 0 && !seen[include] {
+				seen[include] = true
+				includes = append(includes, include)
+			}
+		}
+		for _, nest := range msg.NestedType {
+			walk(nest)
+		}
+	}
+	for _, msg := range file.MessageType {
+		walk(msg)
+	}
+	return includes
+}
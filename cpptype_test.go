@@ -0,0 +1,26 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+//go:generate protoc -Itestdata -I. --include_imports --include_source_info --descriptor_set_out=testdata/cpptypes.pb testdata/cpptypes.proto
+//go:generate protoc -Itestdata -I. --include_imports --include_source_info --descriptor_set_out=testdata/cpptypes_errors.pb testdata/cpptypes_errors.proto
+
+func TestCppTypes(t *testing.T) {
+	header := testGenerate(t, "cpptypes", "")["cpptypes.proto.hpp"]
+	for _, want := range []string{
+		"#include \"my/code.hpp\"\n",
+		"typedef Item_OOneof<::my::Blob, mmdata::SHMString> OOneof;\n            uint16_t id;\n            int8_t small;\n            ::my::Code code;\n            ::my::Blob blob;\n            float ratio;\n",
+		//custom classes without allocator are default constructed
+		"Item(const mmdata::CharAllocator& alloc):id(7),small(0),blob(\"xy\", alloc),ratio(0.0),o(alloc),has_bits_()",
+	} {
+		if !strings.Contains(header, want) {
+			t.Errorf("cpptypes.proto.hpp does not contain %q:\n%s", want, header)
+		}
+	}
+	if n := strings.Count(header, "#include \"my/code.hpp\""); n != 1 {
+		t.Errorf("cpptypes.proto.hpp includes my/code.hpp %d times", n)
+	}
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// defaultText returns the declared default of a field, the proto2 one or the
+// (mmdata.default) option.
+func (g *Generator) defaultText(field *descriptor.FieldDescriptorProto) (string, bool) {
+	if nil != field.DefaultValue {
+		return field.GetDefaultValue(), true
+	}
+	opts := g.fieldOptions(field)
+	if opts.HasDefault {
+		return opts.Default, true
+	}
+	return "", false
+}
+
+// verifyDefaults reports the invalid defaults of the fields of a message and
+// its nested messages.
+func (g *Generator) verifyDefaults(msg *descriptor.DescriptorProto) {
+	for _, nest := range msg.NestedType {
+		g.verifyDefaults(nest)
+	}
+	for _, field := range msg.Field {
+		text, exist := g.defaultText(field)
+		if !exist {
+			continue
+		}
+		if nil != field.DefaultValue && g.fieldOptions(field).HasDefault {
+			g.fieldError(field, "Option (mmdata.default) conflicts with [default = %s]", field.GetDefaultValue())
+			continue
+		}
+		if field.GetLabel() == descriptor.FieldDescriptorProto_LABEL_REPEATED || field.GetType() == descriptor.FieldDescriptorProto_TYPE_MESSAGE {
+			g.fieldError(field, "Option (mmdata.default) is not supported for repeated or message fields")
+			continue
+		}
+		if _, err := g.defaultLiteral(field, text); err != nil {
+			g.fieldError(field, "Invalid option (mmdata.default) %q:%v", text, err)
+		}
+	}
+}
+
+// defaultLiteral converts the default of a field into a C++ expression, for
+// strings the arguments given to the SHMString before the allocator.
+func (g *Generator) defaultLiteral(field *descriptor.FieldDescriptorProto, text string) (string, error) {
+	invalid := fmt.Errorf("not a valid %s", protoTypeName(field.GetType()))
+	switch field.GetType() {
+	case descriptor.FieldDescriptorProto_TYPE_DOUBLE, descriptor.FieldDescriptorProto_TYPE_FLOAT:
+		v, err := floatLiteral(field, text)
+		if err != nil {
+			return "", invalid
+		}
+		return v, nil
+	case descriptor.FieldDescriptorProto_TYPE_INT64, descriptor.FieldDescriptorProto_TYPE_SFIXED64, descriptor.FieldDescriptorProto_TYPE_SINT64:
+		v, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return "", invalid
+		}
+		if v == math.MinInt64 {
+			return "(-9223372036854775807LL - 1)", nil
+		}
+		return fmt.Sprintf("%dLL", v), nil
+	case descriptor.FieldDescriptorProto_TYPE_INT32, descriptor.FieldDescriptorProto_TYPE_SFIXED32, descriptor.FieldDescriptorProto_TYPE_SINT32:
+		v, err := strconv.ParseInt(text, 10, 32)
+		if err != nil {
+			return "", invalid
+		}
+		if v == math.MinInt32 {
+			return "(-2147483647 - 1)", nil
+		}
+		return fmt.Sprintf("%d", v), nil
+	case descriptor.FieldDescriptorProto_TYPE_UINT64, descriptor.FieldDescriptorProto_TYPE_FIXED64:
+		v, err := strconv.ParseUint(text, 10, 64)
+		if err != nil {
+			return "", invalid
+		}
+		return fmt.Sprintf("%dULL", v), nil
+	case descriptor.FieldDescriptorProto_TYPE_UINT32, descriptor.FieldDescriptorProto_TYPE_FIXED32:
+		v, err := strconv.ParseUint(text, 10, 32)
+		if err != nil {
+			return "", invalid
+		}
+		return fmt.Sprintf("%du", v), nil
+	case descriptor.FieldDescriptorProto_TYPE_BOOL:
+		if text != "true" && text != "false" {
+			return "", invalid
+		}
+		return text, nil
+	case descriptor.FieldDescriptorProto_TYPE_ENUM:
+		t := g.registry.Enum(field.GetTypeName())
+		if nil == t {
+			return "", fmt.Errorf("unknown enum %s", field.GetTypeName())
+		}
+		for _, v := range t.Desc.Value {
+			if v.GetName() == text {
+				return g.cppEnumValue(t, v), nil
+			}
+		}
+		return "", fmt.Errorf("no value %s in enum %s", text, t.Desc.GetName())
+	case descriptor.FieldDescriptorProto_TYPE_STRING:
+		return stringLiteral([]byte(text)), nil
+	case descriptor.FieldDescriptorProto_TYPE_BYTES:
+		data, err := unescapeBytes(text)
+		if err != nil {
+			return "", err
+		}
+		return stringLiteral(data), nil
+	}
+	return "", fmt.Errorf("no default for type %v", field.GetType())
+}
+
+func floatLiteral(field *descriptor.FieldDescriptorProto, text string) (string, error) {
+	ctype := "double"
+	bits := 64
+	if field.GetType() == descriptor.FieldDescriptorProto_TYPE_FLOAT {
+		ctype = "float"
+		bits = 32
+	}
+	switch text {
+	case "inf":
+		return fmt.Sprintf("std::numeric_limits<%s>::infinity()", ctype), nil
+	case "-inf":
+		return fmt.Sprintf("-std::numeric_limits<%s>::infinity()", ctype), nil
+	case "nan":
+		return fmt.Sprintf("std::numeric_limits<%s>::quiet_NaN()", ctype), nil
+	}
+	v, err := strconv.ParseFloat(text, bits)
+	if err != nil {
+		return "", err
+	}
+	s := strconv.FormatFloat(v, 'g', -1, bits)
+	if !strings.ContainsAny(s, ".e") {
+		s = s + ".0"
+	}
+	return s, nil
+}
+
+// stringLiteral returns a C++ string literal, followed by its length if it
+// has NUL characters.
+func stringLiteral(data []byte) string {
+	buf := &bytes.Buffer{}
+	buf.WriteByte('"')
+	for _, c := range data {
+		switch {
+		case c == '"' || c == '\\':
+			fmt.Fprintf(buf, "\\%c", c)
+		case c >= 0x20 && c < 0x7f && c != '?':
+			buf.WriteByte(c)
+		default:
+			//3 octal digits never run into the next character
+			fmt.Fprintf(buf, "\\%03o", c)
+		}
+	}
+	buf.WriteByte('"')
+	if bytes.IndexByte(data, 0) >= 0 {
+		fmt.Fprintf(buf, ", %d", len(data))
+	}
+	return buf.String()
+}
+
+// unescapeBytes decodes a C-escaped bytes default, like "\001\377abc".
+func unescapeBytes(s string) ([]byte, error) {
+	var data []byte
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			data = append(data, s[i])
+			continue
+		}
+		i++
+		if i >= len(s) {
+			return nil, fmt.Errorf("trailing backslash")
+		}
+		switch c := s[i]; c {
+		case 'a':
+			data = append(data, '\a')
+		case 'b':
+			data = append(data, '\b')
+		case 'f':
+			data = append(data, '\f')
+		case 'n':
+			data = append(data, '\n')
+		case 'r':
+			data = append(data, '\r')
+		case 't':
+			data = append(data, '\t')
+		case 'v':
+			data = append(data, '\v')
+		case '\\', '\'', '"', '?':
+			data = append(data, c)
+		case 'x', 'X':
+			j := i + 1
+			for j < len(s) && j < i+3 && strings.IndexByte("0123456789abcdefABCDEF", s[j]) >= 0 {
+				j++
+			}
+			if j == i+1 {
+				return nil, fmt.Errorf("invalid escape \\%c", c)
+			}
+			v, _ := strconv.ParseUint(s[i+1:j], 16, 8)
+			data = append(data, byte(v))
+			i = j - 1
+		default:
+			j := i
+			for j < len(s) && j < i+3 && s[j] >= '0' && s[j] <= '7' {
+				j++
+			}
+			if j == i {
+				return nil, fmt.Errorf("invalid escape \\%c", c)
+			}
+			v, err := strconv.ParseUint(s[i:j], 8, 8)
+			if err != nil {
+				return nil, err
+			}
+			data = append(data, byte(v))
+			i = j - 1
+		}
+	}
+	return data, nil
+}
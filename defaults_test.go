@@ -0,0 +1,144 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+func TestDefaultLiteral(t *testing.T) {
+	color := &descriptor.EnumDescriptorProto{
+		Name: proto.String("Color"),
+		Value: []*descriptor.EnumValueDescriptorProto{
+			{Name: proto.String("RED"), Number: proto.Int32(0)},
+			{Name: proto.String("BLUE"), Number: proto.Int32(1)},
+		},
+	}
+	g, file := testGenerator(DefaultConfig())
+	file.EnumType = append(file.EnumType, color)
+	g.registry = NewRegistry([]*descriptor.FileDescriptorProto{file})
+
+	tests := []struct {
+		typ  descriptor.FieldDescriptorProto_Type
+		text string
+		want string
+		err  string
+	}{
+		{descriptor.FieldDescriptorProto_TYPE_INT32, "-12", "-12", ""},
+		{descriptor.FieldDescriptorProto_TYPE_INT32, "010", "10", ""},
+		{descriptor.FieldDescriptorProto_TYPE_INT32, "0x10", "", "not a valid int32"},
+		{descriptor.FieldDescriptorProto_TYPE_INT64, "010", "10LL", ""},
+		{descriptor.FieldDescriptorProto_TYPE_UINT64, "010", "10ULL", ""},
+		{descriptor.FieldDescriptorProto_TYPE_FIXED32, "010", "10u", ""},
+		{descriptor.FieldDescriptorProto_TYPE_SINT32, "-2147483648", "(-2147483647 - 1)", ""},
+		{descriptor.FieldDescriptorProto_TYPE_INT32, "2147483648", "", "not a valid int32"},
+		{descriptor.FieldDescriptorProto_TYPE_INT64, "-9223372036854775808", "(-9223372036854775807LL - 1)", ""},
+		{descriptor.FieldDescriptorProto_TYPE_SFIXED64, "42", "42LL", ""},
+		{descriptor.FieldDescriptorProto_TYPE_UINT32, "4294967295", "4294967295u", ""},
+		{descriptor.FieldDescriptorProto_TYPE_UINT32, "-1", "", "not a valid uint32"},
+		{descriptor.FieldDescriptorProto_TYPE_FIXED64, "18446744073709551615", "18446744073709551615ULL", ""},
+		{descriptor.FieldDescriptorProto_TYPE_BOOL, "true", "true", ""},
+		{descriptor.FieldDescriptorProto_TYPE_BOOL, "1", "", "not a valid bool"},
+		{descriptor.FieldDescriptorProto_TYPE_DOUBLE, "1", "1.0", ""},
+		{descriptor.FieldDescriptorProto_TYPE_FLOAT, "x", "", "not a valid float"},
+		{descriptor.FieldDescriptorProto_TYPE_STRING, "a\"b", `"a\"b"`, ""},
+		{descriptor.FieldDescriptorProto_TYPE_BYTES, `\000x`, `"\000x", 2`, ""},
+		{descriptor.FieldDescriptorProto_TYPE_BYTES, `\q`, "", `invalid escape \q`},
+		{descriptor.FieldDescriptorProto_TYPE_ENUM, "BLUE", "::demo::Color::BLUE", ""},
+		{descriptor.FieldDescriptorProto_TYPE_ENUM, "GREEN", "", "no value GREEN in enum Color"},
+	}
+	for _, test := range tests {
+		field := &descriptor.FieldDescriptorProto{Name: proto.String("f"), Type: test.typ.Enum()}
+		if test.typ == descriptor.FieldDescriptorProto_TYPE_ENUM {
+			field.TypeName = proto.String(".demo.Color")
+		}
+		got, err := g.defaultLiteral(field, test.text)
+		if len(test.err) > 0 {
+			if nil == err || err.Error() != test.err {
+				t.Errorf("defaultLiteral(%v, %q) error = %v, want %q", test.typ, test.text, err, test.err)
+			}
+			continue
+		}
+		if err != nil || got != test.want {
+			t.Errorf("defaultLiteral(%v, %q) = %q, %v, want %q", test.typ, test.text, got, err, test.want)
+		}
+	}
+}
+
+func TestFloatLiteral(t *testing.T) {
+	tests := []struct {
+		typ  descriptor.FieldDescriptorProto_Type
+		text string
+		want string
+	}{
+		{descriptor.FieldDescriptorProto_TYPE_DOUBLE, "1.5", "1.5"},
+		{descriptor.FieldDescriptorProto_TYPE_DOUBLE, "-3", "-3.0"},
+		{descriptor.FieldDescriptorProto_TYPE_DOUBLE, "1e300", "1e+300"},
+		{descriptor.FieldDescriptorProto_TYPE_FLOAT, "0.1", "0.1"},
+		{descriptor.FieldDescriptorProto_TYPE_DOUBLE, "inf", "std::numeric_limits<double>::infinity()"},
+		{descriptor.FieldDescriptorProto_TYPE_FLOAT, "-inf", "-std::numeric_limits<float>::infinity()"},
+		{descriptor.FieldDescriptorProto_TYPE_DOUBLE, "nan", "std::numeric_limits<double>::quiet_NaN()"},
+	}
+	for _, test := range tests {
+		field := &descriptor.FieldDescriptorProto{Type: test.typ.Enum()}
+		got, err := floatLiteral(field, test.text)
+		if err != nil || got != test.want {
+			t.Errorf("floatLiteral(%v, %q) = %q, %v, want %q", test.typ, test.text, got, err, test.want)
+		}
+	}
+	field := &descriptor.FieldDescriptorProto{Type: descriptor.FieldDescriptorProto_TYPE_FLOAT.Enum()}
+	if _, err := floatLiteral(field, "1e39"); nil == err {
+		t.Errorf("floatLiteral(float, \"1e39\") out of range with no error")
+	}
+}
+
+func TestUnescapeBytes(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+		err  string
+	}{
+		{"abc", "abc", ""},
+		{`\001\377`, "\x01\xff", ""},
+		{`\0`, "\x00", ""},
+		{`\1234`, "S4", ""},
+		{`\x41\xff\x4`, "A\xff\x04", ""},
+		{`\x414`, "A4", ""},
+		{`\a\b\f\n\r\t\v`, "\a\b\f\n\r\t\v", ""},
+		{`\\\'\"\?`, `\'"?`, ""},
+		{`\`, "", "trailing backslash"},
+		{`\xg`, "", `invalid escape \x`},
+		{`\8`, "", `invalid escape \8`},
+		{`\400`, "", `strconv.ParseUint: parsing "400": value out of range`},
+	}
+	for _, test := range tests {
+		got, err := unescapeBytes(test.text)
+		if len(test.err) > 0 {
+			if nil == err || err.Error() != test.err {
+				t.Errorf("unescapeBytes(%q) error = %v, want %q", test.text, err, test.err)
+			}
+			continue
+		}
+		if err != nil || string(got) != test.want {
+			t.Errorf("unescapeBytes(%q) = %q, %v, want %q", test.text, got, err, test.want)
+		}
+	}
+}
+
+func TestStringLiteral(t *testing.T) {
+	tests := []struct {
+		data string
+		want string
+	}{
+		{"", `""`},
+		{`a"b\c`, `"a\"b\\c"`},
+		{"a?\n", `"a\077\012"`},
+		{"\x001", `"\0001", 2`},
+	}
+	for _, test := range tests {
+		if got := stringLiteral([]byte(test.data)); got != test.want {
+			t.Errorf("stringLiteral(%q) = %s, want %s", test.data, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// field numbers used by SourceCodeInfo paths
+const (
+	fileMessageTypePath = 4
+	fileEnumTypePath    = 5
+	messageFieldPath    = 2
+	messageNestedPath   = 3
+	messageEnumPath     = 4
+)
+
+// Diagnostic is a schema problem found in a proto file.
+type Diagnostic struct {
+	File    string
+	Message string
+	Field   string
+	//1-based, 0 if the file has no SourceCodeInfo
+	Line, Column int
+	Text         string
+}
+
+func (d *Diagnostic) String() string {
+	buf := &bytes.Buffer{}
+	if len(d.File) > 0 {
+		fmt.Fprintf(buf, "%s:", d.File)
+	}
+	if d.Line > 0 {
+		fmt.Fprintf(buf, "%d:%d:", d.Line, d.Column)
+	}
+	if len(d.Message) > 0 {
+		fmt.Fprintf(buf, " %s", d.Message)
+		if len(d.Field) > 0 {
+			fmt.Fprintf(buf, ".%s", d.Field)
+		}
+		fmt.Fprintf(buf, ":")
+	}
+	if buf.Len() > 0 {
+		fmt.Fprintf(buf, " ")
+	}
+	fmt.Fprintf(buf, "%s", d.Text)
+	return buf.String()
+}
+
+// Diagnostics collects every problem of a run, so that all of them are
+// reported at once through CodeGeneratorResponse.Error.
+type Diagnostics struct {
+	items []*Diagnostic
+	seen  map[string]bool
+	//number of Add calls, repeated problems included
+	count int
+}
+
+// Add records a problem. A problem found again, like the one of a message
+// imported by several generated files, is reported once.
+func (d *Diagnostics) Add(diag *Diagnostic) {
+	d.count++
+	if nil == d.seen {
+		d.seen = make(map[string]bool)
+	}
+	s := diag.String()
+	if d.seen[s] {
+		return
+	}
+	d.seen[s] = true
+	d.items = append(d.items, diag)
+}
+
+// Len returns the number of problems reported.
+func (d *Diagnostics) Len() int {
+	return len(d.items)
+}
+
+// Count returns the number of problems added, repeated ones included, to
+// tell whether a check found any of them.
+func (d *Diagnostics) Count() int {
+	return d.count
+}
+
+func (d *Diagnostics) Error() string {
+	buf := &bytes.Buffer{}
+	for i, diag := range d.items {
+		if i > 0 {
+			fmt.Fprintf(buf, "\n")
+		}
+		fmt.Fprintf(buf, "%s", diag.String())
+	}
+	return buf.String()
+}
+
+// sourceLocation returns the 1-based line/column of the element at path.
+func sourceLocation(file *descriptor.FileDescriptorProto, path []int32) (int, int) {
+	for _, loc := range file.GetSourceCodeInfo().GetLocation() {
+		if len(loc.Path) != len(path) || len(loc.Span) < 2 {
+			continue
+		}
+		match := true
+		for i := range path {
+			if loc.Path[i] != path[i] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return int(loc.Span[0]) + 1, int(loc.Span[1]) + 1
+		}
+	}
+	return 0, 0
+}
+
+// messageError reports a problem of a message, named by its fully-qualified
+// name like "pkg.Outer.Inner".
+func (g *Generator) messageError(msg *descriptor.DescriptorProto, format string, args ...interface{}) {
+	d := &Diagnostic{Message: msg.GetName(), Text: fmt.Sprintf(format, args...)}
+	t := g.registry.MessageOf(msg)
+	if nil != t {
+		d.File = t.File.GetName()
+		d.Message = t.FullName[1:]
+		d.Line, d.Column = sourceLocation(t.File, t.Path)
+	}
+	g.diag.Add(d)
+}
+
+// fieldError reports a problem of a field, named like "pkg.Outer.Inner.field".
+func (g *Generator) fieldError(field *descriptor.FieldDescriptorProto, format string, args ...interface{}) {
+	d := &Diagnostic{Field: field.GetName(), Text: fmt.Sprintf(format, args...)}
+	t, idx := g.registry.FieldOwner(field)
+	if nil != t {
+		d.File = t.File.GetName()
+		d.Message = t.FullName[1:]
+		path := append(append([]int32{}, t.Path...), messageFieldPath, int32(idx))
+		d.Line, d.Column = sourceLocation(t.File, path)
+	}
+	g.diag.Add(d)
+}
@@ -0,0 +1,18 @@
+package main
+
+import "testing"
+
+func TestDiagnosticsReportedOnce(t *testing.T) {
+	d := &Diagnostics{}
+	d.Add(&Diagnostic{File: "a.proto", Message: "pkg.M", Field: "f", Line: 3, Column: 5, Text: "bad"})
+	d.Add(&Diagnostic{File: "a.proto", Message: "pkg.M", Field: "f", Line: 3, Column: 5, Text: "bad"})
+	d.Add(&Diagnostic{File: "a.proto", Message: "pkg.M", Field: "g", Line: 4, Column: 5, Text: "bad"})
+	d.Add(&Diagnostic{File: "a.proto", Message: "pkg.M", Field: "f", Line: 3, Column: 5, Text: "worse"})
+	if d.Len() != 3 || d.Count() != 4 {
+		t.Errorf("Len() = %d and Count() = %d, want 3 and 4", d.Len(), d.Count())
+	}
+	want := "a.proto:3:5: pkg.M.f: bad\na.proto:4:5: pkg.M.g: bad\na.proto:3:5: pkg.M.f: worse"
+	if got := d.Error(); got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// verifyDuplicateKey reports the (mmdata.duplicate_key) not supported by the
+// root table of an entry message.
+func (g *Generator) verifyDuplicateKey(msg *descriptor.DescriptorProto, kv KeyValueFiled) {
+	msgOpts := g.messageOptions(msg)
+	if !msgOpts.HasDuplicateKey {
+		return
+	}
+	if len(kv.Keys) == 0 || len(kv.Values) == 0 {
+		g.messageError(msg, "Option (mmdata.duplicate_key) is only for messages with [(Key) = true] and [(Value) = true] fields")
+		return
+	}
+	if msgOpts.Table == TableMulti {
+		g.messageError(msg, "Option (mmdata.duplicate_key) is not supported for tables with (mmdata.table) = MULTI")
+		return
+	}
+	switch msgOpts.DuplicateKey {
+	case DuplicateKeepLast:
+		if len(g.tableIndexes(kv)) > 0 {
+			g.messageError(msg, "Option (mmdata.duplicate_key) = %v is not supported for tables with (mmdata.index)", msgOpts.DuplicateKey)
+		}
+	case DuplicateMerge:
+		if nil == kv.Value || kv.Value.GetLabel() != descriptor.FieldDescriptorProto_LABEL_REPEATED || g.isMapField(kv.Value) {
+			g.messageError(msg, "Option (mmdata.duplicate_key) = %v is only for a repeated [(Value) = true] field", msgOpts.DuplicateKey)
+		} else if g.isFixedField(kv.Value) {
+			g.messageError(msg, "Option (mmdata.duplicate_key) = %v is not supported for (mmdata.fixed_size) fields", msgOpts.DuplicateKey)
+		}
+	}
+}
+
+// dumpInsert emits the Insert of a root table with no index, which counts the
+// entries of a key already inserted in the BuildContext of the build.
+func (g *Generator) dumpInsert(buf *bytes.Buffer, msg *descriptor.DescriptorProto, kv KeyValueFiled, currentTAB string) {
+	funcTab := currentTAB + "    "
+	policy := g.messageOptions(msg).DuplicateKey
+	fmt.Fprintf(buf, "%sbool Insert(const %s& entry)\n", currentTAB, msg.GetName())
+	fmt.Fprintf(buf, "%s{\n", currentTAB)
+	g.dumpPerfectHashInsert(buf, msg, funcTab)
+	fmt.Fprintf(buf, "%sstd::pair<iterator, bool> ret = insert(value_type(entry.GetKey(), entry.GetValue()));\n", funcTab)
+	fmt.Fprintf(buf, "%sif(ret.second) return true;\n", funcTab)
+	fmt.Fprintf(buf, "%smmdata::pb::BuildContext::CountDuplicateKey();\n", funcTab)
+	switch policy {
+	case DuplicateKeepLast:
+		fmt.Fprintf(buf, "%sret.first->second = entry.GetValue();\n", funcTab)
+		fmt.Fprintf(buf, "%sreturn true;\n", funcTab)
+	case DuplicateMerge:
+		fmt.Fprintf(buf, "%sret.first->second.insert(ret.first->second.end(), entry.%s.begin(), entry.%s.end());\n", funcTab, kv.Value.GetName(), kv.Value.GetName())
+		fmt.Fprintf(buf, "%sreturn true;\n", funcTab)
+	default:
+		fmt.Fprintf(buf, "%sreturn false;\n", funcTab)
+	}
+	fmt.Fprintf(buf, "%s}\n", currentTAB)
+}
+
+// dumpDuplicateReport emits the report of the duplicate keys counted during
+// the Build of a root table in ctx to err, failing the build with FAIL_BUILD.
+func (g *Generator) dumpDuplicateReport(buf *bytes.Buffer, msg *descriptor.DescriptorProto, currentTAB string) {
+	policy := g.messageOptions(msg).DuplicateKey
+	done := map[DuplicateKey]string{
+		DuplicateKeepFirst: "kept the first entries",
+		DuplicateKeepLast:  "kept the last entries",
+		DuplicateMerge:     "merged the values",
+		DuplicateFailBuild: "failed the build",
+	}
+	fmt.Fprintf(buf, "%sif(ctx.duplicate_keys > 0)\n", currentTAB)
+	fmt.Fprintf(buf, "%s{\n", currentTAB)
+	fmt.Fprintf(buf, "%s    std::ostringstream report;\n", currentTAB)
+	fmt.Fprintf(buf, "%s    if(!err.empty()) report<<\"; \";\n", currentTAB)
+	fmt.Fprintf(buf, "%s    report<<ctx.duplicate_keys<<\" duplicate keys in %s, %s (mmdata.duplicate_key = %v)\";\n", currentTAB, msg.GetName(), done[policy], policy)
+	fmt.Fprintf(buf, "%s    err += report.str();\n", currentTAB)
+	if policy == DuplicateFailBuild {
+		fmt.Fprintf(buf, "%s    ret = -1;\n", currentTAB)
+	}
+	fmt.Fprintf(buf, "%s}\n", currentTAB)
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+//go:generate protoc -Itestdata -I. --include_imports --include_source_info --descriptor_set_out=testdata/duplicates.pb testdata/duplicates.proto
+//go:generate protoc -Itestdata -I. --include_imports --include_source_info --descriptor_set_out=testdata/duplicates_errors.pb testdata/duplicates_errors.proto
+
+func TestDuplicateKeys(t *testing.T) {
+	files := testGenerate(t, "duplicates", "")
+	header := files["duplicates.proto.hpp"]
+	for _, want := range []string{
+		"if(ret.second) return true;\n                mmdata::pb::BuildContext::CountDuplicateKey();\n                return false;\n",
+		"ret.first->second = entry.GetValue();\n                return true;\n",
+		"ret.first->second.insert(ret.first->second.end(), entry.v.begin(), entry.v.end());\n",
+		"uint64_t duplicate_keys;",
+	} {
+		if !strings.Contains(header, want) {
+			t.Errorf("duplicates.proto.hpp does not contain %q:\n%s", want, header)
+		}
+	}
+	source := files["duplicates.proto.cpp"]
+	for _, want := range []string{
+		"report<<ctx.duplicate_keys<<\" duplicate keys in First, kept the first entries (mmdata.duplicate_key = KEEP_FIRST)\";",
+		"report<<ctx.duplicate_keys<<\" duplicate keys in Merge, merged the values (mmdata.duplicate_key = MERGE)\";",
+		"already inserted\";\n                    err += report.str();\n                    ret = -1;\n",
+	} {
+		if !strings.Contains(source, want) {
+			t.Errorf("duplicates.proto.cpp does not contain %q:\n%s", want, source)
+		}
+	}
+	if strings.Contains(header, "DuplicateKeys()") {
+		t.Errorf("duplicates.proto.hpp counts the duplicate keys out of a BuildContext:\n%s", header)
+	}
+}
+
+func TestDuplicateKeysBuild(t *testing.T) {
+	testCppChecks(t, "duplicates")
+}
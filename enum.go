@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// enumLocalName returns the C++ name of an enum in its namespace, nested enums
+// are mangled like Outer_Color and typedef-ed in the struct of their message.
+func enumLocalName(t *EnumType) string {
+	return strings.Replace(t.LocalName(), ".", "_", -1)
+}
+
+// cppEnumName returns the C++ name of an enum type, qualified with its
+// namespace when the type is declared in another package.
+func (g *Generator) cppEnumName(name string) string {
+	t := g.registry.Enum(name)
+	if nil == t {
+		return g.TypeName(name)
+	}
+	if t.Package() == g.packageName {
+		return enumLocalName(t)
+	}
+	return g.cppNamespace(t.Package()) + "::" + enumLocalName(t)
+}
+
+// enumValueName returns the C++ name of an enum value. Values of plain nested
+// enums are prefixed like Outer_RED, since they are not scoped by the enum.
+func (g *Generator) enumValueName(t *EnumType, v *descriptor.EnumValueDescriptorProto) string {
+	local := enumLocalName(t)
+	if g.config.EnumClass || !strings.Contains(t.LocalName(), ".") {
+		return local + "::" + v.GetName()
+	}
+	parent := local[:len(local)-len(t.Desc.GetName())]
+	return local + "::" + parent + v.GetName()
+}
+
+// cppEnumValue returns the C++ name of an enum value, qualified with its
+// namespace when the enum is declared in another package.
+func (g *Generator) cppEnumValue(t *EnumType, v *descriptor.EnumValueDescriptorProto) string {
+	return g.cppEnumName(t.FullName) + g.enumValueName(t, v)[len(enumLocalName(t)):]
+}
+
+// DumpEnums emits all enums declared in the file, followed by their kcfg json
+// mapping which has to be in namespace kcfg.
+func (g *Generator) DumpEnums(file *descriptor.FileDescriptorProto) {
+	enums := g.registry.FileEnums(file)
+	if len(enums) == 0 {
+		return
+	}
+	buf := &g.OutputBuffer
+	tab, tabs := g.dumpNamespaceOpen(buf, file.GetPackage())
+	for _, t := range enums {
+		g.dumpEnum(buf, t, tab)
+	}
+	g.dumpNamespaceClose(buf, tabs)
+
+	fmt.Fprintf(buf, "namespace kcfg\n{\n")
+	for _, t := range enums {
+		g.dumpEnumKcfg(buf, t, "    ")
+	}
+	fmt.Fprintf(buf, "}\n\n")
+}
+
+func (g *Generator) dumpEnum(buf *bytes.Buffer, t *EnumType, currentTAB string) {
+	name := enumLocalName(t)
+	funcTab := currentTAB + "    "
+	caseTab := funcTab + "    "
+	if g.config.EnumClass {
+		fmt.Fprintf(buf, "%senum class %s : int32_t\n", currentTAB, name)
+	} else {
+		fmt.Fprintf(buf, "%senum %s : int32_t\n", currentTAB, name)
+	}
+	fmt.Fprintf(buf, "%s{\n", currentTAB)
+	for _, v := range t.Desc.Value {
+		valueName := g.enumValueName(t, v)
+		fmt.Fprintf(buf, "%s%s = %d,\n", funcTab, valueName[len(name)+2:], v.GetNumber())
+	}
+	fmt.Fprintf(buf, "%s};\n", currentTAB)
+
+	//values with allow_alias share the number of the first one
+	var values []*descriptor.EnumValueDescriptorProto
+	numbers := make(map[int32]bool)
+	for _, v := range t.Desc.Value {
+		if !numbers[v.GetNumber()] {
+			numbers[v.GetNumber()] = true
+			values = append(values, v)
+		}
+	}
+
+	fmt.Fprintf(buf, "%sinline const char* %s_Name(%s v)\n", currentTAB, name, name)
+	fmt.Fprintf(buf, "%s{\n", currentTAB)
+	fmt.Fprintf(buf, "%sswitch(v)\n", funcTab)
+	fmt.Fprintf(buf, "%s{\n", funcTab)
+	for _, v := range values {
+		fmt.Fprintf(buf, "%scase %s: return \"%s\";\n", caseTab, g.enumValueName(t, v), v.GetName())
+	}
+	fmt.Fprintf(buf, "%sdefault: return \"\";\n", caseTab)
+	fmt.Fprintf(buf, "%s}\n", funcTab)
+	fmt.Fprintf(buf, "%s}\n", currentTAB)
+
+	fmt.Fprintf(buf, "%sinline bool %s_Parse(const std::string& name, %s& v)\n", currentTAB, name, name)
+	fmt.Fprintf(buf, "%s{\n", currentTAB)
+	for _, v := range t.Desc.Value {
+		fmt.Fprintf(buf, "%sif(name == \"%s\") { v = %s; return true; }\n", funcTab, v.GetName(), g.enumValueName(t, v))
+	}
+	fmt.Fprintf(buf, "%sreturn false;\n", funcTab)
+	fmt.Fprintf(buf, "%s}\n", currentTAB)
+
+	fmt.Fprintf(buf, "%sinline bool %s_IsValid(int32_t v)\n", currentTAB, name)
+	fmt.Fprintf(buf, "%s{\n", currentTAB)
+	fmt.Fprintf(buf, "%sswitch(v)\n", funcTab)
+	fmt.Fprintf(buf, "%s{\n", funcTab)
+	for _, v := range values {
+		fmt.Fprintf(buf, "%scase %d:\n", caseTab, v.GetNumber())
+	}
+	fmt.Fprintf(buf, "%s%sreturn true;\n", caseTab, "    ")
+	fmt.Fprintf(buf, "%sdefault: return false;\n", caseTab)
+	fmt.Fprintf(buf, "%s}\n", funcTab)
+	fmt.Fprintf(buf, "%s}\n", currentTAB)
+
+	fmt.Fprintf(buf, "%sinline std::ostream& operator<<(std::ostream& os, %s v)\n", currentTAB, name)
+	fmt.Fprintf(buf, "%s{\n", currentTAB)
+	fmt.Fprintf(buf, "%sconst char* name = %s_Name(v);\n", funcTab, name)
+	fmt.Fprintf(buf, "%sif(name[0] != 0) os<<name;\n", funcTab)
+	fmt.Fprintf(buf, "%selse os<<static_cast<int32_t>(v);\n", funcTab)
+	fmt.Fprintf(buf, "%sreturn os;\n", funcTab)
+	fmt.Fprintf(buf, "%s}\n\n", currentTAB)
+}
+
+// dumpEnumKcfg maps an enum to its value name in json, a number is accepted
+// when parsing too.
+func (g *Generator) dumpEnumKcfg(buf *bytes.Buffer, t *EnumType, currentTAB string) {
+	ns := g.cppNamespace(t.Package())
+	name := ns + "::" + enumLocalName(t)
+	funcTab := currentTAB + "    "
+	blockTab := funcTab + "    "
+	fmt.Fprintf(buf, "%sinline bool Parse(const rapidjson::Value& json, const char* name, %s& v)\n", currentTAB, name)
+	fmt.Fprintf(buf, "%s{\n", currentTAB)
+	fmt.Fprintf(buf, "%sconst rapidjson::Value* val = &json;\n", funcTab)
+	fmt.Fprintf(buf, "%sif(NULL != name && name[0] != 0)\n", funcTab)
+	fmt.Fprintf(buf, "%s{\n", funcTab)
+	fmt.Fprintf(buf, "%sif(!json.IsObject() || !json.HasMember(name)) return false;\n", blockTab)
+	fmt.Fprintf(buf, "%sval = &json[name];\n", blockTab)
+	fmt.Fprintf(buf, "%s}\n", funcTab)
+	fmt.Fprintf(buf, "%sif(val->IsString()) return %s_Parse(val->GetString(), v);\n", funcTab, name)
+	fmt.Fprintf(buf, "%sif(val->IsInt() && %s_IsValid(val->GetInt()))\n", funcTab, name)
+	fmt.Fprintf(buf, "%s{\n", funcTab)
+	fmt.Fprintf(buf, "%sv = static_cast<%s>(val->GetInt());\n", blockTab, name)
+	fmt.Fprintf(buf, "%sreturn true;\n", blockTab)
+	fmt.Fprintf(buf, "%s}\n", funcTab)
+	fmt.Fprintf(buf, "%sreturn false;\n", funcTab)
+	fmt.Fprintf(buf, "%s}\n", currentTAB)
+
+	fmt.Fprintf(buf, "%sinline void Serialize(rapidjson::Value& json, rapidjson::Value::AllocatorType& allocator, const char* name, const %s& v)\n", currentTAB, name)
+	fmt.Fprintf(buf, "%s{\n", currentTAB)
+	fmt.Fprintf(buf, "%srapidjson::Value val(%s_Name(v), allocator);\n", funcTab, name)
+	fmt.Fprintf(buf, "%sif(NULL != name && name[0] != 0)\n", funcTab)
+	fmt.Fprintf(buf, "%s{\n", funcTab)
+	fmt.Fprintf(buf, "%sjson.AddMember(rapidjson::Value(name, allocator).Move(), val, allocator);\n", blockTab)
+	fmt.Fprintf(buf, "%s}\n", funcTab)
+	fmt.Fprintf(buf, "%selse\n", funcTab)
+	fmt.Fprintf(buf, "%s{\n", funcTab)
+	fmt.Fprintf(buf, "%sjson = val;\n", blockTab)
+	fmt.Fprintf(buf, "%s}\n", funcTab)
+	fmt.Fprintf(buf, "%s}\n", currentTAB)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+//go:generate protoc -Itestdata -I. --include_imports --include_source_info --descriptor_set_out=testdata/enums.pb testdata/enums.proto
+
+func TestEnums(t *testing.T) {
+	tests := []struct {
+		param     string
+		want      []string
+		forbidden []string
+	}{
+		{"", []string{
+			"enum class Color : int32_t\n        {\n            RED = 0,\n            GREEN = 1,\n            VERDE = 1,\n        };",
+			"enum class Item_Kind : int32_t\n        {\n            NONE = 0,\n            BIG = 2,\n        };",
+			"inline bool Color_Parse(const std::string& name, Color& v)",
+			"if(name == \"VERDE\") { v = Color::VERDE; return true; }",
+			"inline bool Item_Kind_IsValid(int32_t v)",
+			"inline std::ostream& operator<<(std::ostream& os, Color v)",
+			"inline bool Parse(const rapidjson::Value& json, const char* name, ::test::enums::Color& v)",
+			"typedef Item_Kind Kind;\n            Item_Kind kind;\n            Color color;",
+		}, []string{
+			//an alias has the name of the first value
+			"case Color::VERDE:",
+		}},
+		{"enum_class=false", []string{
+			"enum Color : int32_t\n",
+			"enum Item_Kind : int32_t\n        {\n            Item_NONE = 0,\n            Item_BIG = 2,\n        };",
+			"case Item_Kind::Item_BIG: return \"BIG\";",
+		}, []string{
+			"enum class",
+		}},
+	}
+	for _, test := range tests {
+		header := testGenerate(t, "enums", test.param)["enums.proto.hpp"]
+		for _, want := range test.want {
+			if !strings.Contains(header, want) {
+				t.Errorf("enums.proto.hpp with %q does not contain %q:\n%s", test.param, want, header)
+			}
+		}
+		for _, forbidden := range test.forbidden {
+			if strings.Contains(header, forbidden) {
+				t.Errorf("enums.proto.hpp with %q contains %q:\n%s", test.param, forbidden, header)
+			}
+		}
+	}
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// fixedType returns the inline type of a field with (mmdata.fixed_size), a
+// FixedArray for a repeated field, a FixedString for a string and a FixedBytes
+// for bytes.
+func (g *Generator) fixedType(field *descriptor.FieldDescriptorProto) (string, bool) {
+	opts := g.fieldOptions(field)
+	if opts.FixedSize == 0 {
+		return "", false
+	}
+	args := fmt.Sprintf("%d", opts.FixedSize)
+	if opts.FixedTruncate {
+		args = args + ", true"
+	}
+	if field.GetLabel() == descriptor.FieldDescriptorProto_LABEL_REPEATED {
+		return fmt.Sprintf("mmdata::pb::FixedArray<%s, %s>", g.getBaseFieldType(field), args), true
+	}
+	if field.GetType() == descriptor.FieldDescriptorProto_TYPE_BYTES {
+		return fmt.Sprintf("mmdata::pb::FixedBytes<%s>", args), true
+	}
+	return fmt.Sprintf("mmdata::pb::FixedString<%s>", args), true
+}
+
+// verifyFixedSizes reports the invalid (mmdata.fixed_size) of the fields of a
+// message and its nested messages.
+func (g *Generator) verifyFixedSizes(msg *descriptor.DescriptorProto) {
+	for _, nest := range msg.NestedType {
+		g.verifyFixedSizes(nest)
+	}
+	for _, field := range msg.Field {
+		opts := g.fieldOptions(field)
+		if opts.FixedSize == 0 {
+			if opts.HasFixedOverflow {
+				g.fieldError(field, "Option (mmdata.fixed_overflow) is only for fields with (mmdata.fixed_size)")
+			}
+			continue
+		}
+		if g.isMapField(field) {
+			g.fieldError(field, "Option (mmdata.fixed_size) is not supported for map fields")
+			continue
+		}
+		if field.GetLabel() == descriptor.FieldDescriptorProto_LABEL_REPEATED {
+			//the elements are stored inline too
+			if g.isComplexValue(field, false) {
+				name := protoTypeName(g.valueField(field).GetType())
+				if len(opts.CppType) > 0 {
+					name = opts.CppType
+				}
+				g.fieldError(field, "Option (mmdata.fixed_size) is not supported for repeated %s fields", name)
+			}
+			continue
+		}
+		if field.GetType() != descriptor.FieldDescriptorProto_TYPE_STRING && field.GetType() != descriptor.FieldDescriptorProto_TYPE_BYTES {
+			g.fieldError(field, "Option (mmdata.fixed_size) is only for repeated, string and bytes fields")
+			continue
+		}
+		if len(opts.CppType) > 0 {
+			g.fieldError(field, "Option (mmdata.fixed_size) conflicts with (mmdata.cpp_type)")
+			continue
+		}
+		text, exist := g.defaultText(field)
+		if !exist {
+			continue
+		}
+		data := []byte(text)
+		if field.GetType() == descriptor.FieldDescriptorProto_TYPE_BYTES {
+			//invalid escapes are reported by verifyDefaults
+			data, _ = unescapeBytes(text)
+		}
+		if len(data) > int(opts.FixedSize) {
+			g.fieldError(field, "Default %q is over (mmdata.fixed_size) %d", text, opts.FixedSize)
+		}
+	}
+}
+
+func (g *Generator) isFixedField(field *descriptor.FieldDescriptorProto) bool {
+	return g.fieldOptions(field).FixedSize > 0
+}
+
+// DumpFixedTypes emits the FixedString and FixedArray templates if the file
+// uses them, guarded like the well-known types.
+func (g *Generator) DumpFixedTypes(file *descriptor.FileDescriptorProto) {
+	if fileHasField(file, g.isFixedField) {
+		fmt.Fprintf(&g.OutputBuffer, "%s\n", fixedTypesCode)
+	}
+}
+
+// fixedTypesCode holds the inline containers of (mmdata.fixed_size), a size
+// followed by the data. Over the capacity, assign()/push_back()/resize() and
+// the kcfg parsing fail, or drop the extra data if Truncate.
+const fixedTypesCode = `#ifndef MMDATA_FIXED_TYPES_
+#define MMDATA_FIXED_TYPES_
+#include <algorithm>
+#include <cstring>
+#include <ostream>
+#include <string>
+namespace mmdata
+{
+    namespace pb
+    {
+        //a string of up to N chars, kept NUL terminated
+        template<uint32_t N, bool Truncate = false>
+        struct FixedString
+        {
+            typedef char value_type;
+            typedef const char* const_iterator;
+            FixedString():size_(0)
+            {
+                data_[0] = 0;
+            }
+            FixedString(const char* s):size_(0)
+            {
+                data_[0] = 0;
+                assign(s);
+            }
+            FixedString(const char* s, size_t n):size_(0)
+            {
+                data_[0] = 0;
+                assign(s, n);
+            }
+            bool assign(const char* s, size_t n)
+            {
+                if(n > N)
+                {
+                    if(!Truncate) return false;
+                    n = N;
+                }
+                memcpy(data_, s, n);
+                data_[n] = 0;
+                size_ = static_cast<uint32_t>(n);
+                return true;
+            }
+            bool assign(const char* s) { return assign(s, strlen(s)); }
+            bool assign(const std::string& s) { return assign(s.data(), s.size()); }
+            void clear()
+            {
+                size_ = 0;
+                data_[0] = 0;
+            }
+            size_t size() const { return size_; }
+            size_t length() const { return size_; }
+            bool empty() const { return size_ == 0; }
+            static size_t capacity() { return N; }
+            const char* data() const { return data_; }
+            const char* c_str() const { return data_; }
+            const_iterator begin() const { return data_; }
+            const_iterator end() const { return data_ + size_; }
+            char operator[](size_t i) const { return data_[i]; }
+            std::string str() const { return std::string(data_, size_); }
+            int compare(const FixedString& other) const
+            {
+                int c = memcmp(data_, other.data_, size_ < other.size_ ? size_ : other.size_);
+                if(c != 0) return c;
+                return size_ < other.size_ ? -1 : (size_ > other.size_ ? 1 : 0);
+            }
+        private:
+            uint32_t size_;
+            char data_[N + 1];
+        };
+        template<uint32_t N, bool Truncate>
+        inline bool operator==(const FixedString<N, Truncate>& a, const FixedString<N, Truncate>& b) { return a.compare(b) == 0; }
+        template<uint32_t N, bool Truncate>
+        inline bool operator!=(const FixedString<N, Truncate>& a, const FixedString<N, Truncate>& b) { return a.compare(b) != 0; }
+        template<uint32_t N, bool Truncate>
+        inline bool operator<(const FixedString<N, Truncate>& a, const FixedString<N, Truncate>& b) { return a.compare(b) < 0; }
+        template<uint32_t N, bool Truncate>
+        inline bool operator>(const FixedString<N, Truncate>& a, const FixedString<N, Truncate>& b) { return a.compare(b) > 0; }
+        template<uint32_t N, bool Truncate>
+        inline std::size_t hash_value(const FixedString<N, Truncate>& v) { return boost::hash_range(v.begin(), v.end()); }
+        template<uint32_t N, bool Truncate>
+        inline std::ostream& operator<<(std::ostream& os, const FixedString<N, Truncate>& v)
+        {
+            return os.write(v.data(), v.size());
+        }
+
+        //an array of up to N elements
+        template<typename T, uint32_t N, bool Truncate = false>
+        struct FixedArray
+        {
+            typedef T value_type;
+            typedef T* iterator;
+            typedef const T* const_iterator;
+            FixedArray():size_(0)
+            {}
+            bool push_back(const T& v)
+            {
+                if(size_ == N) return Truncate;
+                data_[size_++] = v;
+                return true;
+            }
+            void pop_back() { size_--; }
+            bool resize(size_t n)
+            {
+                if(n > N)
+                {
+                    if(!Truncate) return false;
+                    n = N;
+                }
+                for(size_t i = size_; i < n; i++) data_[i] = T();
+                size_ = static_cast<uint32_t>(n);
+                return true;
+            }
+            void clear() { size_ = 0; }
+            size_t size() const { return size_; }
+            bool empty() const { return size_ == 0; }
+            static size_t capacity() { return N; }
+            const T* data() const { return data_; }
+            iterator begin() { return data_; }
+            iterator end() { return data_ + size_; }
+            const_iterator begin() const { return data_; }
+            const_iterator end() const { return data_ + size_; }
+            T& operator[](size_t i) { return data_[i]; }
+            const T& operator[](size_t i) const { return data_[i]; }
+        private:
+            uint32_t size_;
+            T data_[N];
+        };
+        template<typename T, uint32_t N, bool Truncate>
+        inline bool operator==(const FixedArray<T, N, Truncate>& a, const FixedArray<T, N, Truncate>& b)
+        {
+            return a.size() == b.size() && std::equal(a.begin(), a.end(), b.begin());
+        }
+        template<typename T, uint32_t N, bool Truncate>
+        inline bool operator!=(const FixedArray<T, N, Truncate>& a, const FixedArray<T, N, Truncate>& b) { return !(a == b); }
+        template<typename T, uint32_t N, bool Truncate>
+        inline bool operator<(const FixedArray<T, N, Truncate>& a, const FixedArray<T, N, Truncate>& b)
+        {
+            return std::lexicographical_compare(a.begin(), a.end(), b.begin(), b.end());
+        }
+        template<typename T, uint32_t N, bool Truncate>
+        inline bool operator>(const FixedArray<T, N, Truncate>& a, const FixedArray<T, N, Truncate>& b) { return b < a; }
+        template<typename T, uint32_t N, bool Truncate>
+        inline std::size_t hash_value(const FixedArray<T, N, Truncate>& v) { return boost::hash_range(v.begin(), v.end()); }
+        template<typename T, uint32_t N, bool Truncate>
+        inline std::ostream& operator<<(std::ostream& os, const FixedArray<T, N, Truncate>& v)
+        {
+            os<<"[";
+            for(size_t i = 0; i < v.size(); i++)
+            {
+                if(i > 0) os<<",";
+                os<<v[i];
+            }
+            return os<<"]";
+        }
+    }
+}
+namespace kcfg
+{
+    template<uint32_t N, bool Truncate>
+    inline bool Parse(const rapidjson::Value& json, const char* name, mmdata::pb::FixedString<N, Truncate>& v)
+    {
+        std::string s;
+        if(!Parse(json, name, s)) return false;
+        return v.assign(s);
+    }
+    template<uint32_t N, bool Truncate>
+    inline void Serialize(rapidjson::Value& json, rapidjson::Value::AllocatorType& allocator, const char* name, const mmdata::pb::FixedString<N, Truncate>& v)
+    {
+        Serialize(json, allocator, name, v.str());
+    }
+    template<typename T, uint32_t N, bool Truncate>
+    inline bool Parse(const rapidjson::Value& json, const char* name, mmdata::pb::FixedArray<T, N, Truncate>& v)
+    {
+        const rapidjson::Value* val = &json;
+        if(NULL != name && name[0] != 0)
+        {
+            if(!json.IsObject() || !json.HasMember(name)) return false;
+            val = &json[name];
+        }
+        if(!val->IsArray()) return false;
+        v.clear();
+        for(rapidjson::Value::ConstValueIterator it = val->Begin(); it != val->End(); ++it)
+        {
+            T item = T();
+            if(!Parse(*it, "", item) || !v.push_back(item)) return false;
+        }
+        return true;
+    }
+    template<typename T, uint32_t N, bool Truncate>
+    inline void Serialize(rapidjson::Value& json, rapidjson::Value::AllocatorType& allocator, const char* name, const mmdata::pb::FixedArray<T, N, Truncate>& v)
+    {
+        rapidjson::Value val(rapidjson::kArrayType);
+        for(size_t i = 0; i < v.size(); i++)
+        {
+            rapidjson::Value item;
+            Serialize(item, allocator, "", v[i]);
+            val.PushBack(item, allocator);
+        }
+        if(NULL != name && name[0] != 0)
+        {
+            json.AddMember(rapidjson::Value(name, allocator).Move(), val, allocator);
+        }
+        else
+        {
+            json = val;
+        }
+    }
+}
+#endif /* MMDATA_FIXED_TYPES_ */
+`
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+//go:generate protoc -Itestdata -I. --include_imports --include_source_info --descriptor_set_out=testdata/fixed.pb testdata/fixed.proto
+//go:generate protoc -Itestdata -I. --include_imports --include_source_info --descriptor_set_out=testdata/fixed_errors.pb testdata/fixed_errors.proto
+
+func TestFixedSize(t *testing.T) {
+	header := testGenerate(t, "fixed", "")["fixed.proto.hpp"]
+	tests := []struct {
+		field string
+		want  string
+	}{
+		{"code", "mmdata::pb::FixedString<8> code;"},
+		{"ids", "mmdata::pb::FixedArray<int32_t, 4> ids;"},
+		{"colors", "mmdata::pb::FixedArray<Color, 2, true> colors;"},
+		{"raw", "mmdata::pb::FixedBytes<4, true> raw;"},
+		{"times", "mmdata::pb::FixedArray<mmdata::pb::Timestamp, 3> times;"},
+		{"small", "mmdata::pb::FixedArray<uint16_t, 3> small;"},
+		{"a", "typedef Item_OOneof<mmdata::pb::FixedString<2>, int32_t> OOneof;"},
+		//inline fields take no allocator
+		{"code", "Item(const mmdata::CharAllocator& alloc):code(\"abc\"),o(alloc),has_bits_()"},
+		{"Entry.k", "typedef mmdata::pb::FixedString<16> key_type;"},
+		{"KeyEntry.v", "typedef mmdata::pb::FixedArray<int32_t, 16> value_type;"},
+	}
+	for _, test := range tests {
+		if !strings.Contains(header, test.want) {
+			t.Errorf("fixed.proto.hpp does not contain %q for %s:\n%s", test.want, test.field, header)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// verifyFlat reports the options of a root table not supported by the
+// (mmdata.container) = FLAT tables.
+func (g *Generator) verifyFlat(msg *descriptor.DescriptorProto) {
+	msgOpts := g.messageOptions(msg)
+	if msgOpts.Eytzinger && msgOpts.Container != ContainerFlat {
+		g.messageError(msg, "Option (mmdata.eytzinger) is only for tables with (mmdata.container) = FLAT")
+	}
+	if msgOpts.Container == ContainerFlat && msgOpts.Table == TableMulti {
+		g.messageError(msg, "Option (mmdata.container) = FLAT is not supported for tables with (mmdata.table) = MULTI")
+	}
+}
+
+// DumpFlatTableTypes emits mmdata::pb::FlatTable if a root table of the file
+// is a FLAT one.
+func (g *Generator) DumpFlatTableTypes(file *descriptor.FileDescriptorProto) {
+	for _, msg := range file.MessageType {
+		if _, haveKeyFiled := g.hashEntryMessages[msg]; haveKeyFiled && g.messageOptions(msg).Container == ContainerFlat {
+			fmt.Fprintf(&g.OutputBuffer, "%s\n", flatTableTypesCode)
+			return
+		}
+	}
+}
+
+// flatTableTypesCode holds mmdata::pb::FlatTable, the root table of entries
+// sorted by key in a single vector, with the find/iteration API of the map
+// containers for the readers. The builder gives no hook at the end of the
+// build, so each insert leaves a table ready to read: the entry is inserted
+// at its place, in O(1) for entries in key order.
+//
+// The Eytzinger layout holds the positions of the entries, not copies of
+// their keys, in the order of a breadth-first walk of the binary search tree,
+// so that the top levels of a lookup stay in cache. It is rebuilt after an
+// insert among the entries it covers, and once the entries appended after it
+// are over 1/8 of the table, which are then found by a binary search.
+const flatTableTypesCode = `#ifndef MMDATA_FLAT_TABLE_TYPES_
+#define MMDATA_FLAT_TABLE_TYPES_
+#include <algorithm>
+#include <utility>
+namespace mmdata
+{
+    namespace pb
+    {
+        template<typename K, typename V, bool Eytzinger = false>
+        struct FlatTable
+        {
+            typedef K key_type;
+            typedef V mapped_type;
+            typedef std::pair<K, V> value_type;
+            typedef typename mmdata::SHMVector<value_type>::Type Entries;
+            typedef typename Entries::iterator iterator;
+            typedef typename Entries::const_iterator const_iterator;
+            typedef typename Entries::size_type size_type;
+            typedef typename Entries::allocator_type allocator_type;
+
+            Entries entries_;
+            //the positions of the first layout_.size() entries in Eytzinger order
+            typename mmdata::SHMVector<uint32_t>::Type layout_;
+
+            FlatTable(const mmdata::CharAllocator& alloc):entries_(alloc),layout_(alloc)
+            {}
+
+            iterator begin() { return entries_.begin(); }
+            iterator end() { return entries_.end(); }
+            const_iterator begin() const { return entries_.begin(); }
+            const_iterator end() const { return entries_.end(); }
+            size_type size() const { return entries_.size(); }
+            bool empty() const { return entries_.empty(); }
+            allocator_type get_allocator() const { return entries_.get_allocator(); }
+
+            iterator find(const K& key)
+            {
+                size_t pos = LowerBound(key);
+                if(pos == entries_.size() || key < entries_[pos].first) return end();
+                return begin() + pos;
+            }
+            const_iterator find(const K& key) const
+            {
+                size_t pos = LowerBound(key);
+                if(pos == entries_.size() || key < entries_[pos].first) return end();
+                return begin() + pos;
+            }
+            size_type count(const K& key) const { return find(key) == end() ? 0 : 1; }
+
+            std::pair<iterator, bool> insert(const value_type& entry)
+            {
+                size_t pos = entries_.size();
+                if(!entries_.empty() && !(entries_.back().first < entry.first))
+                {
+                    pos = LowerBound(entry.first);
+                    if(!(entry.first < entries_[pos].first)) return std::make_pair(begin() + pos, false);
+                }
+                entries_.insert(begin() + pos, entry);
+                if(Eytzinger && (pos < layout_.size() || 8 * (entries_.size() - layout_.size()) > entries_.size()))
+                {
+                    Layout();
+                }
+                return std::make_pair(begin() + pos, true);
+            }
+
+        private:
+            struct KeyLess
+            {
+                bool operator()(const value_type& entry, const K& key) const { return entry.first < key; }
+            };
+            //the position of the first entry not less than key
+            size_t LowerBound(const K& key) const
+            {
+                size_t n = layout_.size();
+                if(n > 0 && !(entries_[n - 1].first < key))
+                {
+                    size_t i = 1;
+                    while(i <= n) i = 2 * i + (entries_[layout_[i - 1]].first < key ? 1 : 0);
+                    //drops the right turns after the last left one
+                    while(i & 1) i >>= 1;
+                    i >>= 1;
+                    return layout_[i - 1];
+                }
+                return std::lower_bound(entries_.begin() + n, entries_.end(), key, KeyLess()) - entries_.begin();
+            }
+            void Layout()
+            {
+                layout_.resize(entries_.size());
+                uint32_t pos = 0;
+                Layout(pos, 1);
+            }
+            //the positions of the entries in the in-order walk of the tree
+            void Layout(uint32_t& pos, size_t i)
+            {
+                if(i > layout_.size()) return;
+                Layout(pos, 2 * i);
+                layout_[i - 1] = pos++;
+                Layout(pos, 2 * i + 1);
+            }
+        };
+    }
+}
+#endif /* MMDATA_FLAT_TABLE_TYPES_ */
+`
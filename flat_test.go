@@ -0,0 +1,36 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+//go:generate protoc -Itestdata -I. --include_imports --include_source_info --descriptor_set_out=testdata/flat.pb testdata/flat.proto
+//go:generate protoc -Itestdata -I. --include_imports --include_source_info --descriptor_set_out=testdata/flat_errors.pb testdata/flat_errors.proto
+
+func TestFlatTables(t *testing.T) {
+	files := testGenerate(t, "flat", "")
+	header := files["flat.proto.hpp"]
+	for _, want := range []string{
+		"struct FlatTable\n",
+		"typedef mmdata::pb::FlatTable<int64_t, mmdata::SHMString> PlainTableParent;",
+		"typedef mmdata::pb::FlatTable<int64_t, Item, true> EytTableParent;",
+		"typedef mmdata::pb::FlatTable<Comp::key_type, int32_t> CompTableParent;",
+		"typedef mmdata::SHMHashMap<mmdata::SHMString, mmdata::SHMVector<key_type>::Type>::Type SIndex;",
+	} {
+		if !strings.Contains(header, want) {
+			t.Errorf("flat.proto.hpp does not contain %q:\n%s", want, header)
+		}
+	}
+	if strings.Count(header, "struct FlatTable\n") != 1 {
+		t.Errorf("flat.proto.hpp does not hold a single FlatTable:\n%s", header)
+	}
+	//the table is ready to read after each insert, with nothing to run once built
+	if source := files["flat.proto.cpp"]; strings.Contains(source, "Finish") {
+		t.Errorf("flat.proto.cpp finishes the tables after the build:\n%s", source)
+	}
+}
+
+func TestFlatTablesBuild(t *testing.T) {
+	testCppChecks(t, "flat")
+}
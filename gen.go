@@ -4,15 +4,18 @@ import (
 	"bytes"
 	"fmt"
 	"hash/crc64"
-	"log"
 	"strings"
 
-	"github.com/gogo/protobuf/proto"
+	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/protoc-gen-go/descriptor"
 )
 
 type KeyValueFiled struct {
 	Key, Value *descriptor.FieldDescriptorProto
+	//the fields of the key in key order, Key is nil for a composite key
+	Keys []*descriptor.FieldDescriptorProto
+	//the fields of the value, Value is nil for a composite value
+	Values []*descriptor.FieldDescriptorProto
 }
 
 type Generator struct {
@@ -22,13 +25,20 @@ type Generator struct {
 	dumpCppName  string
 	//dumpDescName string
 	macroName string
-	msgTypes  map[string]*descriptor.DescriptorProto
+	config    *Config
+	registry  *Registry
+	diag      *Diagnostics
 	HashValue uint64
 	//keyField, valueField *descriptor.FieldDescriptorProto
-	hashEntryMessages map[string]KeyValueFiled
+	hashEntryMessages map[*descriptor.DescriptorProto]KeyValueFiled
 	packageName       string
 
 	hashGened map[string]bool
+
+	msgOptions map[*descriptor.DescriptorProto]*MessageOptions
+	fieldOpts  map[*descriptor.FieldDescriptorProto]*FieldOptions
+
+	nestedOrder map[*descriptor.DescriptorProto]messageOrder
 }
 
 func (g *Generator) isHashGened(f string) bool {
@@ -41,73 +51,114 @@ func (g *Generator) isHashGened(f string) bool {
 
 func (g *Generator) Verify(file *descriptor.FileDescriptorProto) bool {
 	//log.Printf("####%s", file.GetName())
-	g.hashEntryMessages = make(map[string]KeyValueFiled)
+	g.hashEntryMessages = make(map[*descriptor.DescriptorProto]KeyValueFiled)
 	g.hashGened = make(map[string]bool)
+	errors := g.diag.Count()
 	for _, msg := range file.MessageType {
 		kv := KeyValueFiled{}
+		msgOpts := g.messageOptions(msg)
 		for _, field := range msg.GetField() {
-			if nil != field.GetOptions() {
-				opstr := strings.TrimSpace(field.GetOptions().String())
-				if strings.Contains(opstr, "51234:1") {
-					if nil != kv.Key {
-						log.Fatalf("Duplicate filed with option: [(Key) = true]")
-						return false
-					}
-					kv.Key = field
-				} else if strings.Contains(opstr, "51235:1") {
-					if nil != kv.Value {
-						log.Fatalf("Duplicate filed with option:  [(Value) = true]")
-						return false
-					}
-					kv.Value = field
+			opts := g.fieldOptions(field)
+			if (opts.Key || opts.Value) && isOneofField(field) {
+				g.fieldError(field, "Option [(Key) = true] or [(Value) = true] is not supported on oneof fields")
+				continue
+			}
+			if opts.HasKeyOrder && !opts.Key {
+				g.fieldError(field, "Option (mmdata.key_order) is only for fields with [(Key) = true]")
+			}
+			if opts.Key {
+				kv.Keys = append(kv.Keys, field)
+			} else if opts.Value || msgOpts.AllValues {
+				if opts.Value && msgOpts.AllValues {
+					g.fieldError(field, "Option [(Value) = true] conflicts with (mmdata.all_values)")
 				}
+				kv.Values = append(kv.Values, field)
 			}
 		}
-		if nil != kv.Key && nil != kv.Value {
-			g.hashEntryMessages[msg.GetName()] = kv
-		} else if nil != kv.Key || nil != kv.Value {
-			log.Fatalf("Missing filed with option: [(Key) = true] or [(Value) = true]")
-			return false
+		g.sortKeys(msg, kv.Keys)
+		if len(kv.Keys) == 1 {
+			kv.Key = kv.Keys[0]
 		}
+		//a oneof is a member of a composite value even alone
+		if len(kv.Values) == 1 && !isOneofField(kv.Values[0]) {
+			kv.Value = kv.Values[0]
+		}
+		if len(kv.Keys) > 0 && len(kv.Values) > 0 {
+			g.hashEntryMessages[msg] = kv
+		} else if len(kv.Keys) > 0 || len(kv.Values) > 0 {
+			g.messageError(msg, "Missing filed with option: [(Key) = true] or [(Value) = true]")
+		}
+		if msgOpts.HasContainer {
+			if len(kv.Keys) == 0 || len(kv.Values) == 0 {
+				g.messageError(msg, "Option (mmdata.container) is only for messages with [(Key) = true] and [(Value) = true] fields")
+			} else if msgOpts.Container == ContainerFlatSorted {
+				g.messageError(msg, "Option (mmdata.container) = %v is not supported for root tables, use FLAT", msgOpts.Container)
+			}
+		}
+		if msgOpts.HasTable && (len(kv.Keys) == 0 || len(kv.Values) == 0) {
+			g.messageError(msg, "Option (mmdata.table) is only for messages with [(Key) = true] and [(Value) = true] fields")
+		}
+		g.verifyDefaults(msg)
+		g.verifyWellKnownTypes(msg)
+		g.verifyCppTypes(msg)
+		g.verifyFixedSizes(msg)
+		g.verifyMaps(msg, kv)
+		g.verifyIndexes(msg, kv)
+		g.verifyDuplicateKey(msg, kv)
+		g.verifyFlat(msg)
+		g.verifyPerfectHash(msg)
 	}
-	if len(g.hashEntryMessages) == 0 {
-		//log.Printf("Maybe missing message with fileds with Options [(Key) = true] or [(Value) = true]")
-		return false
-	}
+	return g.diag.Count() == errors
+}
 
-	return true
+func (g *Generator) getDesc(name string) *descriptor.DescriptorProto {
+	t := g.registry.Message(name)
+	if nil != t {
+		return t.Desc
+	}
+	return nil
 }
 
-func (g *Generator) BuildTypeNameMap(file *descriptor.FileDescriptorProto) {
-	if nil == g.msgTypes {
-		g.msgTypes = make(map[string]*descriptor.DescriptorProto)
+// cppTypeName returns the C++ name of a message type, qualified with its
+// namespace when the type is declared in another package.
+func (g *Generator) cppTypeName(name string) string {
+	t := g.registry.Message(name)
+	if nil == t {
+		return g.TypeName(name)
 	}
-	dottedPkg := "." + file.GetPackage()
-	for _, msg := range file.MessageType {
-		name := dottedPkg + "." + msg.GetName()
-		g.msgTypes[name] = msg
-		for _, nest := range msg.NestedType {
-			g.msgTypes[name+"."+nest.GetName()] = nest
-		}
+	if t.Package() == g.packageName {
+		return g.localTypeName(t)
 	}
+	return g.cppNamespace(t.Package()) + "::" + g.localTypeName(t)
 }
 
-func (g *Generator) getDesc(name string) *descriptor.DescriptorProto {
-	desc, exist := g.msgTypes[name]
-	if exist {
-		return desc
+// cppNamespace converts a proto package into a C++ namespace, "a.b" => "::a::b".
+func (g *Generator) cppNamespace(pkg string) string {
+	ns := ""
+	for _, s := range g.config.Namespaces(pkg) {
+		ns = ns + "::" + s
 	}
-	return nil
+	return ns
 }
 
 func (g *Generator) NestMarshal(msg *descriptor.DescriptorProto) []byte {
+	return g.nestMarshal(msg, make(map[*descriptor.DescriptorProto]bool))
+}
+
+// nestMarshal skips the messages being marshaled, which are used recursively.
+func (g *Generator) nestMarshal(msg *descriptor.DescriptorProto, marshaling map[*descriptor.DescriptorProto]bool) []byte {
+	marshaling[msg] = true
+	defer delete(marshaling, msg)
 	buf := &bytes.Buffer{}
 	data, _ := proto.Marshal(msg)
 	buf.Write(data)
+	if len(g.presenceFields(msg)) > 0 {
+		buf.Write(hasBitsLayout)
+	}
 	for _, f := range msg.Field {
 		fdesc := g.getDesc(f.GetTypeName())
-		if nil != fdesc {
-			data = g.NestMarshal(fdesc)
+		if nil != fdesc && !marshaling[fdesc] {
+			data = g.nestMarshal(fdesc, marshaling)
 			buf.Write(data)
 		}
 	}
@@ -131,15 +182,13 @@ func (g *Generator) TypeName(name string) string {
 	return last
 }
 
-func (g *Generator) DumpHeader(pbfile string) {
-	fname := pbfile
-	if strings.Contains(fname, "/") {
-		idx := strings.LastIndex(fname, "/")
-		fname = fname[idx+1 : len(fname)]
-	}
-	g.dumpFileName = fname + ".hpp"
-	g.dumpCppName = fname + ".cpp"
-	g.macroName = strings.ToUpper(pbfile+".hpp") + "_"
+func (g *Generator) DumpHeader(file *descriptor.FileDescriptorProto) {
+	pbfile := file.GetName()
+	g.packageName = file.GetPackage()
+	fname := g.config.OutputName(file)
+	g.dumpFileName = fname + g.config.HeaderExt
+	g.dumpCppName = fname + g.config.SourceExt
+	g.macroName = strings.ToUpper(pbfile+g.config.HeaderExt) + "_"
 	g.macroName = strings.Replace(g.macroName, ".", "_", -1)
 	g.macroName = strings.Replace(g.macroName, "/", "_", -1)
 	fmt.Fprintf(&g.OutputBuffer, "// Generated by the plugin protoc-gen-mmadata of protocol buffer compiler.  DO NOT EDIT!\n")
@@ -148,15 +197,106 @@ func (g *Generator) DumpHeader(pbfile string) {
 	fmt.Fprintf(&g.OutputBuffer, "#ifndef %s\n", g.macroName)
 	fmt.Fprintf(&g.OutputBuffer, "#define %s\n", g.macroName)
 	fmt.Fprintf(&g.OutputBuffer, "#include <iosfwd>\n")
-	fmt.Fprintf(&g.OutputBuffer, "#include \"kcfg.hpp\"\n")
-	fmt.Fprintf(&g.OutputBuffer, "#include \"mmdata.hpp\"\n")
-	fmt.Fprintf(&g.OutputBuffer, "#include \"mmdata_kcfg.hpp\"\n\n")
+	fmt.Fprintf(&g.OutputBuffer, "#include <new>\n")
+	fmt.Fprintf(&g.OutputBuffer, "#include <limits>\n")
+	if g.fileHasMultiIndex(file) {
+		fmt.Fprintf(&g.OutputBuffer, "#include <vector>\n")
+	}
+	fmt.Fprintf(&g.OutputBuffer, "#include \"%skcfg.hpp\"\n", g.config.IncludePrefix)
+	fmt.Fprintf(&g.OutputBuffer, "#include \"%smmdata.hpp\"\n", g.config.IncludePrefix)
+	fmt.Fprintf(&g.OutputBuffer, "#include \"%smmdata_kcfg.hpp\"\n\n", g.config.IncludePrefix)
 
 	fmt.Fprintf(&g.CppBuffer, "// Generated by the plugin protoc-gen-mmadata of protocol buffer compiler.  DO NOT EDIT!\n")
 	fmt.Fprintf(&g.CppBuffer, "//  source: %s\n\n", pbfile)
 	fmt.Fprintf(&g.CppBuffer, "#include <iostream>\n")
+	fmt.Fprintf(&g.CppBuffer, "#include <sstream>\n")
 	fmt.Fprintf(&g.CppBuffer, "#include \"%s\"\n", g.dumpFileName)
-	fmt.Fprintf(&g.CppBuffer, "#include \"mmdata_util.hpp\"\n\n")
+	fmt.Fprintf(&g.CppBuffer, "#include \"%smmdata_util.hpp\"\n\n", g.config.IncludePrefix)
+}
+
+// DumpImports includes the headers generated for every imported proto file,
+// mmdata_base.proto and the google/protobuf files have no generated header,
+// and the headers of the (mmdata.cpp_type) of the fields.
+func (g *Generator) DumpImports(file *descriptor.FileDescriptorProto) {
+	count := 0
+	for _, dep := range file.GetDependency() {
+		if dep == "mmdata_base.proto" || strings.HasPrefix(dep, "google/protobuf/") {
+			continue
+		}
+		depFile := g.registry.File(dep)
+		if nil == depFile {
+			continue
+		}
+		fmt.Fprintf(&g.OutputBuffer, "#include \"%s%s\"\n", g.config.OutputName(depFile), g.config.HeaderExt)
+		count++
+	}
+	for _, include := range g.cppTypeIncludes(file) {
+		if !strings.HasPrefix(include, "<") && !strings.HasPrefix(include, "\"") {
+			include = "\"" + include + "\""
+		}
+		fmt.Fprintf(&g.OutputBuffer, "#include %s\n", include)
+		count++
+	}
+	if count > 0 {
+		fmt.Fprintf(&g.OutputBuffer, "\n")
+	}
+}
+
+// DumpImportedKeyHelpers emits the key helpers of key types declared in
+// imported files, and of the messages they hold. They are put in the namespace
+// of the type for boost::hash to find them by ADL, and guarded since several
+// headers may use the same key.
+func (g *Generator) DumpImportedKeyHelpers(file *descriptor.FileDescriptorProto) {
+	for _, msg := range file.MessageType {
+		kv, haveKeyFiled := g.hashEntryMessages[msg]
+		if !haveKeyFiled {
+			continue
+		}
+		for _, t := range g.keyHelperTypes(kv.Keys...) {
+			keyType := g.cppTypeName(t.FullName)
+			if t.File == file || g.isHashGened(keyType) {
+				continue
+			}
+			guard := "MMDATA_KEY_HELPERS" + strings.ToUpper(strings.Replace(t.FullName, ".", "_", -1)) + "_"
+			fmt.Fprintf(&g.OutputBuffer, "#ifndef %s\n", guard)
+			fmt.Fprintf(&g.OutputBuffer, "#define %s\n", guard)
+			tab, tabs := g.dumpNamespaceOpen(&g.OutputBuffer, t.Package())
+			g.dumpKeyHelpers(&g.OutputBuffer, keyType, structMembers(t.Desc), false, tab)
+			g.dumpNamespaceClose(&g.OutputBuffer, tabs)
+			fmt.Fprintf(&g.OutputBuffer, "#endif /* %s */\n\n", guard)
+			g.hashGened[keyType] = true
+		}
+	}
+}
+
+// dumpNamespaceOpen opens the namespaces of a package in a single buffer,
+// for the blocks of the header out of the namespaces of the file.
+func (g *Generator) dumpNamespaceOpen(buf *bytes.Buffer, pkg string) (string, []string) {
+	var tabs []string
+	tab := ""
+	for _, ns := range g.config.Namespaces(pkg) {
+		fmt.Fprintf(buf, "%snamespace %s\n%s{\n", tab, ns, tab)
+		tabs = append(tabs, tab)
+		tab = "    " + tab
+	}
+	return tab, tabs
+}
+
+// namespaceTabs returns the indents of namespaces, like dumpNamespaceOpen.
+func namespaceTabs(namespaces []string) []string {
+	var tabs []string
+	tab := ""
+	for range namespaces {
+		tabs = append(tabs, tab)
+		tab = "    " + tab
+	}
+	return tabs
+}
+
+func (g *Generator) dumpNamespaceClose(buf *bytes.Buffer, tabs []string) {
+	for i := len(tabs) - 1; i >= 0; i-- {
+		fmt.Fprintf(buf, "%s}\n", tabs[i])
+	}
 }
 
 func (g *Generator) Finish() {
@@ -164,7 +304,7 @@ func (g *Generator) Finish() {
 }
 
 func (g *Generator) DumpNamespaceBegin(name string) (string, []string) {
-	ss := strings.Split(name, ".")
+	ss := g.config.Namespaces(name)
 	var tabs []string
 	tab := ""
 
@@ -173,12 +313,8 @@ func (g *Generator) DumpNamespaceBegin(name string) (string, []string) {
 		fmt.Fprintf(&g.CppBuffer, "%snamespace %s\n%s{\n", tab, ns, tab)
 		tabs = append(tabs, tab)
 		tab = "    " + tab
-		if len(g.packageName) == 0 {
-			g.packageName = ns
-		} else {
-			g.packageName = g.packageName + "." + ns
-		}
 	}
+	g.packageName = name
 	return tab, tabs
 }
 
@@ -190,6 +326,14 @@ func (g *Generator) DumpNamespaceEnd(tabs []string) {
 }
 
 func (g *Generator) getBaseFieldType(field *descriptor.FieldDescriptorProto) string {
+	if t := g.fieldOptions(field).CppType; len(t) > 0 {
+		return t
+	}
+	if field.GetLabel() != descriptor.FieldDescriptorProto_LABEL_REPEATED {
+		if t, exist := g.fixedType(field); exist {
+			return t
+		}
+	}
 	switch field.GetType() {
 	case descriptor.FieldDescriptorProto_TYPE_DOUBLE:
 		return "double"
@@ -214,11 +358,11 @@ func (g *Generator) getBaseFieldType(field *descriptor.FieldDescriptorProto) str
 	case descriptor.FieldDescriptorProto_TYPE_STRING:
 		return "mmdata::SHMString"
 	case descriptor.FieldDescriptorProto_TYPE_BYTES:
-		return "mmdata::SHMString"
+		return "mmdata::pb::Bytes"
 	case descriptor.FieldDescriptorProto_TYPE_UINT32:
 		return "uint32_t"
 	case descriptor.FieldDescriptorProto_TYPE_ENUM:
-		return field.GetTypeName()
+		return g.cppEnumName(field.GetTypeName())
 	case descriptor.FieldDescriptorProto_TYPE_SFIXED32:
 		return "int32_t"
 	case descriptor.FieldDescriptorProto_TYPE_SFIXED64:
@@ -228,38 +372,53 @@ func (g *Generator) getBaseFieldType(field *descriptor.FieldDescriptorProto) str
 	case descriptor.FieldDescriptorProto_TYPE_SINT64:
 		return "int64_t"
 	case descriptor.FieldDescriptorProto_TYPE_MESSAGE:
-		return g.TypeName(field.GetTypeName())
+		if v := g.wrappedField(field); nil != v {
+			return g.getBaseFieldType(v)
+		}
+		if t, exist := wellKnownStructs[field.GetTypeName()]; exist {
+			return t
+		}
+		return g.cppTypeName(field.GetTypeName())
 	default:
-		log.Fatalf("Not supported type:%v", field.GetTypeName())
+		g.fieldError(field, "Not supported type:%v", field.GetType())
 	}
 	return ""
 }
 
+// mapEntry returns the map entry message of a map field, or nil.
+func (g *Generator) mapEntry(field *descriptor.FieldDescriptorProto) *descriptor.DescriptorProto {
+	if field.GetLabel() != descriptor.FieldDescriptorProto_LABEL_REPEATED || field.GetType() != descriptor.FieldDescriptorProto_TYPE_MESSAGE {
+		return nil
+	}
+	desc := g.getDesc(field.GetTypeName())
+	if nil != desc && desc.GetOptions().GetMapEntry() {
+		return desc
+	}
+	return nil
+}
+
+func (g *Generator) isMapField(field *descriptor.FieldDescriptorProto) bool {
+	return nil != g.mapEntry(field)
+}
+
 func (g *Generator) getFieldType(field *descriptor.FieldDescriptorProto) string {
 	if field.GetLabel() == descriptor.FieldDescriptorProto_LABEL_REPEATED {
-		isMap := false
+		if t, exist := g.fixedType(field); exist {
+			return t
+		}
 		buf := &bytes.Buffer{}
-		if field.GetType() == descriptor.FieldDescriptorProto_TYPE_MESSAGE {
-			desc := g.getDesc(field.GetTypeName())
-			if nil != desc && desc.GetOptions().GetMapEntry() {
-				keyField, valField := desc.Field[0], desc.Field[1]
-				useTreeMap := false
-				if nil != field.GetOptions() {
-					opstr := strings.TrimSpace(field.GetOptions().String())
-					if strings.Contains(opstr, "51236:\"Tree\"") {
-						useTreeMap = true
-					}
-				}
-				if useTreeMap {
-					fmt.Fprintf(buf, "mmdata::SHMMap<%s, %s>::Type", g.getBaseFieldType(keyField), g.getBaseFieldType(valField))
-				} else {
-					fmt.Fprintf(buf, "mmdata::SHMHashMap<%s, %s>::Type", g.getBaseFieldType(keyField), g.getBaseFieldType(valField))
-				}
-
-				isMap = true
+		desc := g.mapEntry(field)
+		if nil != desc {
+			keyField, valField := desc.Field[0], desc.Field[1]
+			switch g.fieldOptions(field).Container {
+			case ContainerTree:
+				fmt.Fprintf(buf, "mmdata::SHMMap<%s, %s>::Type", g.getBaseFieldType(keyField), g.getBaseFieldType(valField))
+			case ContainerFlatSorted:
+				fmt.Fprintf(buf, "mmdata::SHMFlatMap<%s, %s>::Type", g.getBaseFieldType(keyField), g.getBaseFieldType(valField))
+			default:
+				fmt.Fprintf(buf, "mmdata::SHMHashMap<%s, %s>::Type", g.getBaseFieldType(keyField), g.getBaseFieldType(valField))
 			}
-		}
-		if !isMap {
+		} else {
 			fmt.Fprintf(buf, "mmdata::SHMVector<%s>::Type", g.getBaseFieldType(field))
 		}
 		return buf.String()
@@ -267,10 +426,22 @@ func (g *Generator) getFieldType(field *descriptor.FieldDescriptorProto) string
 	return g.getBaseFieldType(field)
 }
 
+// withDefaultValue returns the initial value of a field, the declared default
+// if any. For strings it is only given with a declared default, as the
+// arguments of the SHMString before the allocator.
 func (g *Generator) withDefaultValue(field *descriptor.FieldDescriptorProto) (string, bool) {
 	if field.GetLabel() == descriptor.FieldDescriptorProto_LABEL_REPEATED {
 		return "", false
 	}
+	if text, exist := g.defaultText(field); exist {
+		//invalid defaults are reported by Verify
+		if v, err := g.defaultLiteral(field, text); err == nil {
+			return v, true
+		}
+	}
+	if g.isCustomType(field) {
+		return "", false
+	}
 	switch field.GetType() {
 	case descriptor.FieldDescriptorProto_TYPE_DOUBLE:
 		return "0.0", true
@@ -295,7 +466,12 @@ func (g *Generator) withDefaultValue(field *descriptor.FieldDescriptorProto) (st
 	case descriptor.FieldDescriptorProto_TYPE_UINT32:
 		return "0", true
 	case descriptor.FieldDescriptorProto_TYPE_ENUM:
-		return "", false
+		//the first value, which is 0 in proto3
+		t := g.registry.Enum(field.GetTypeName())
+		if nil == t || len(t.Desc.Value) == 0 {
+			return "", false
+		}
+		return g.cppEnumValue(t, t.Desc.Value[0]), true
 	case descriptor.FieldDescriptorProto_TYPE_SFIXED32:
 		return "0", true
 	case descriptor.FieldDescriptorProto_TYPE_SFIXED64:
@@ -305,17 +481,37 @@ func (g *Generator) withDefaultValue(field *descriptor.FieldDescriptorProto) (st
 	case descriptor.FieldDescriptorProto_TYPE_SINT64:
 		return "0", true
 	case descriptor.FieldDescriptorProto_TYPE_MESSAGE:
+		if v := g.wrappedField(field); nil != v {
+			return g.withDefaultValue(v)
+		}
+		if isWellKnownStruct(field) {
+			return "", false
+		}
 		return "0", true
 	default:
-		log.Fatalf("Not supported type:%v", field.GetTypeName())
+		g.fieldError(field, "Not supported type:%v", field.GetType())
 	}
 	return "", false
 }
 
 func (g *Generator) isComplextType(field *descriptor.FieldDescriptorProto, excludeString bool) bool {
+	if g.fieldOptions(field).FixedSize > 0 {
+		//inline, with no allocator
+		return false
+	}
 	if field.GetLabel() == descriptor.FieldDescriptorProto_LABEL_REPEATED {
 		return true
 	}
+	return g.isComplexValue(field, excludeString)
+}
+
+// isComplexValue is isComplextType for a single value, the element of a
+// repeated field.
+func (g *Generator) isComplexValue(field *descriptor.FieldDescriptorProto, excludeString bool) bool {
+	if opts := g.fieldOptions(field); len(opts.CppType) > 0 {
+		//a custom type is like a string, it has no key helpers to generate
+		return opts.CppTypeAlloc && !excludeString
+	}
 	switch field.GetType() {
 	case descriptor.FieldDescriptorProto_TYPE_DOUBLE:
 		return false
@@ -356,173 +552,459 @@ func (g *Generator) isComplextType(field *descriptor.FieldDescriptorProto, exclu
 	case descriptor.FieldDescriptorProto_TYPE_SINT64:
 		return false
 	case descriptor.FieldDescriptorProto_TYPE_MESSAGE:
-		return true
+		if v := g.wrappedField(field); nil != v {
+			return g.isComplexValue(v, excludeString)
+		}
+		return !isWellKnownStruct(field)
 	default:
-		log.Fatalf("Not supported type:%v", field.GetTypeName())
+		g.fieldError(field, "Not supported type:%v", field.GetType())
 	}
 	return false
 }
 
-func (g *Generator) dumpFieldType(buf *bytes.Buffer, field *descriptor.FieldDescriptorProto) {
-	fmt.Fprintf(buf, g.getFieldType(field))
-}
+// dumpKeyHelpers emits the hash_value/operator==/operator< a message needs
+// to be used as the key of a SHMHashMap/SHMMap. A composite key has no
+// presence, and its hash depends on the order of the members.
+func (g *Generator) dumpKeyHelpers(buf *bytes.Buffer, keyType string, members []member, composite bool, currentTAB string) {
+	fmt.Fprintf(buf, "%sinline std::size_t hash_value(const %s& v)\n", currentTAB, keyType)
+	fmt.Fprintf(buf, "%s{\n", currentTAB)
+	funcTab := currentTAB + "    "
+	fmt.Fprintf(buf, "%sstd::size_t hash = 0;\n", funcTab)
+	for _, m := range members {
+		if m.oneof < 0 && g.isMapField(m.field) {
+			g.dumpMapHash(buf, m.field, m.name, funcTab)
+			continue
+		}
+		//unions have their hash_value found by ADL too
+		hash := fmt.Sprintf("hash_value(v.%s)", m.name)
+		if m.oneof < 0 && !g.hasADLHash(m.field) {
+			hash = fmt.Sprintf("boost::hash_value<%s>(v.%s)", g.getFieldType(m.field), m.name)
+		}
+		if composite {
+			fmt.Fprintf(buf, "%sboost::hash_combine(hash, %s);\n", funcTab, hash)
+			continue
+		}
+		if m.oneof < 0 && g.hasPresence(m.field) {
+			fmt.Fprintf(buf, "%sif(v.has_%s()) hash ^= %s;\n", funcTab, m.name, hash)
+			continue
+		}
+		fmt.Fprintf(buf, "%shash ^= %s;\n", funcTab, hash)
+	}
 
-func (g *Generator) DumpMessage(msg *descriptor.DescriptorProto, currentTAB string) error {
-	buf := &g.OutputBuffer
+	fmt.Fprintf(buf, "%sreturn hash;\n", funcTab)
+	fmt.Fprintf(buf, "%s}\n", currentTAB)
 
-	rootMapType := "Hash"
-	if nil != msg.GetOptions() {
-		opstr := strings.TrimSpace(msg.GetOptions().String())
-		if strings.EqualFold(opstr, "51236:\"Tree\"") {
-			rootMapType = "Tree"
+	fmt.Fprintf(buf, "%sinline bool operator==(const %s& a, const %s& b)\n", currentTAB, keyType, keyType)
+	fmt.Fprintf(buf, "%s{\n", currentTAB)
+	for _, m := range members {
+		//fields not set are equal whatever their values
+		if !composite && m.oneof < 0 && g.hasPresence(m.field) {
+			fmt.Fprintf(buf, "%sif(a.has_%s() != b.has_%s()) return false;\n", funcTab, m.name, m.name)
+			fmt.Fprintf(buf, "%sif(a.has_%s() && !(a.%s == b.%s)) return false;\n", funcTab, m.name, m.name, m.name)
+			continue
 		}
+		fmt.Fprintf(buf, "%sif(!(a.%s == b.%s)) return false;\n", funcTab, m.name, m.name)
 	}
-	tableType := ""
-	kv, haveKeyFiled := g.hashEntryMessages[msg.GetName()]
-	if haveKeyFiled {
-		if g.isComplextType(kv.Key, true) && !g.isHashGened(g.getFieldType(kv.Key)) {
-			fmt.Fprintf(buf, "%sinline std::size_t hash_value(const %s& v)\n", currentTAB, g.getFieldType(kv.Key))
-			fmt.Fprintf(buf, "%s{\n", currentTAB)
-			desc := g.getDesc(kv.Key.GetTypeName())
-			funcTab := currentTAB + "    "
-			fmt.Fprintf(buf, "%sstd::size_t hash = 0;\n", funcTab)
-			for _, kf := range desc.Field {
-				fmt.Fprintf(buf, "%shash ^= boost::hash_value<%s>(v.%s);\n", funcTab, g.getFieldType(kf), kf.GetName())
-			}
+	fmt.Fprintf(buf, "%sreturn true;\n", funcTab)
+	fmt.Fprintf(buf, "%s}\n", currentTAB)
 
-			fmt.Fprintf(buf, "%sreturn hash;\n", funcTab)
-			fmt.Fprintf(buf, "%s}\n", currentTAB)
-
-			fmt.Fprintf(buf, "%sinline bool operator==(const %s& a, const %s& b)\n", currentTAB, g.getFieldType(kv.Key), g.getFieldType(kv.Key))
-			fmt.Fprintf(buf, "%s{\n", currentTAB)
-			for _, kf := range desc.Field {
-				fmt.Fprintf(buf, "%sif(!(a.%s == b.%s)) return false;\n", funcTab, kf.GetName(), kf.GetName())
-			}
-			fmt.Fprintf(buf, "%sreturn true;\n", funcTab)
-			fmt.Fprintf(buf, "%s}\n", currentTAB)
-
-			fmt.Fprintf(buf, "%sinline bool operator<(const %s& a, const %s& b)\n", currentTAB, g.getFieldType(kv.Key), g.getFieldType(kv.Key))
-			fmt.Fprintf(buf, "%s{\n", currentTAB)
-			for _, kf := range desc.Field {
-				fmt.Fprintf(buf, "%sif((a.%s < b.%s)) return true;\n", funcTab, kf.GetName(), kf.GetName())
-				fmt.Fprintf(buf, "%sif((a.%s > b.%s)) return false;\n", funcTab, kf.GetName(), kf.GetName())
-			}
-			fmt.Fprintf(buf, "%sreturn false;\n", funcTab)
-			fmt.Fprintf(buf, "%s}\n", currentTAB)
-			g.hashGened[g.getFieldType(kv.Key)] = true
+	fmt.Fprintf(buf, "%sinline bool operator<(const %s& a, const %s& b)\n", currentTAB, keyType, keyType)
+	fmt.Fprintf(buf, "%s{\n", currentTAB)
+	for _, m := range members {
+		fieldTab := funcTab
+		//a field not set is less than a set one
+		if !composite && m.oneof < 0 && g.hasPresence(m.field) {
+			fmt.Fprintf(buf, "%sif(a.has_%s() != b.has_%s()) return b.has_%s();\n", funcTab, m.name, m.name, m.name)
+			fmt.Fprintf(buf, "%sif(a.has_%s())\n", funcTab, m.name)
+			fmt.Fprintf(buf, "%s{\n", funcTab)
+			fieldTab = funcTab + "    "
 		}
+		fmt.Fprintf(buf, "%sif((a.%s < b.%s)) return true;\n", fieldTab, m.name, m.name)
+		//unions and messages only have operator<
+		fmt.Fprintf(buf, "%sif((b.%s < a.%s)) return false;\n", fieldTab, m.name, m.name)
+		if fieldTab != funcTab {
+			fmt.Fprintf(buf, "%s}\n", funcTab)
+		}
+	}
+	fmt.Fprintf(buf, "%sreturn false;\n", funcTab)
+	fmt.Fprintf(buf, "%s}\n", currentTAB)
+}
 
-		tableType = fmt.Sprintf("%sTable", msg.GetName())
-		fmt.Fprintf(buf, "%sstruct %s;\n", currentTAB, tableType)
+// hasADLHash returns whether the hash_value of a field type is found by ADL,
+// the types declared out of boost which are not containers.
+func (g *Generator) hasADLHash(field *descriptor.FieldDescriptorProto) bool {
+	if g.fieldOptions(field).FixedSize > 0 {
+		return true
+	}
+	if field.GetLabel() == descriptor.FieldDescriptorProto_LABEL_REPEATED {
+		return false
 	}
+	return isWellKnownStruct(field) || g.isCustomType(field) || g.isBytesField(field) || nil != g.heldMessage(field)
+}
 
-	fmt.Fprintf(buf, "%sstruct %s\n", currentTAB, msg.GetName())
+// dumpStruct emits the struct of a message, in nested struct mode the nested
+// messages are emitted inside it.
+func (g *Generator) dumpStruct(buf *bytes.Buffer, msg *descriptor.DescriptorProto, currentTAB string) {
+	kv, haveKeyFiled := g.hashEntryMessages[msg]
+	fmt.Fprintf(buf, "%sstruct %s\n", currentTAB, g.structName(msg))
 	fmt.Fprintf(buf, "%s{\n", currentTAB)
 	fieldTab := currentTAB + "    "
 	var fields string
 
+	if g.config.Nested == nestedMangle {
+		for _, nest := range nestedTypes(msg) {
+			fmt.Fprintf(buf, "%stypedef %s %s;\n", fieldTab, g.messageName(nest), nest.GetName())
+		}
+	} else {
+		sorted, forwards := g.sortNestedTypes(msg)
+		for _, nest := range sorted {
+			g.dumpForwards(buf, forwards[nest], fieldTab)
+			g.dumpStruct(buf, nest, fieldTab)
+			fmt.Fprintf(buf, "\n")
+		}
+	}
+	if t := g.registry.MessageOf(msg); nil != t {
+		for _, enum := range msg.EnumType {
+			fmt.Fprintf(buf, "%stypedef %s %s;\n", fieldTab, g.cppEnumName(t.FullName+"."+enum.GetName()), enum.GetName())
+		}
+	}
+	for _, i := range oneofIndexes(msg) {
+		fmt.Fprintf(buf, "%stypedef %s %s;\n", fieldTab, g.oneofType(msg, i), oneofLocalName(msg.OneofDecl[i]))
+	}
 	if haveKeyFiled {
-		fmt.Fprintf(buf, "%stypedef %s key_type;\n", fieldTab, g.getFieldType(kv.Key))
-		fmt.Fprintf(buf, "%stypedef %s value_type;\n", fieldTab, g.getFieldType(kv.Value))
-		fmt.Fprintf(buf, "%stypedef %s table_type;\n", fieldTab, tableType)
+		if nil == kv.Key {
+			g.dumpMembersStruct(buf, msg, "key_type", keyMembers(kv.Keys), fieldTab)
+		} else {
+			fmt.Fprintf(buf, "%stypedef %s key_type;\n", fieldTab, g.getFieldType(kv.Key))
+		}
+		if nil == kv.Value {
+			g.dumpMembersStruct(buf, msg, "value_type", valueMembers(msg, kv.Values), fieldTab)
+		} else {
+			fmt.Fprintf(buf, "%stypedef %s value_type;\n", fieldTab, g.getFieldType(kv.Value))
+		}
+		fmt.Fprintf(buf, "%stypedef %sTable table_type;\n", fieldTab, msg.GetName())
 	}
-	for i, field := range msg.GetField() {
+	for i, m := range structMembers(msg) {
+		field := m.field
 		fmt.Fprintf(buf, "%s", fieldTab)
-		if field == kv.Key {
+		if m.oneof >= 0 {
+			fmt.Fprintf(buf, "%s", oneofLocalName(msg.OneofDecl[m.oneof]))
+		} else if haveKeyFiled && field == kv.Key {
 			fmt.Fprintf(buf, "key_type")
-		} else if field == kv.Value {
+		} else if haveKeyFiled && field == kv.Value {
 			fmt.Fprintf(buf, "value_type")
 		} else {
 			g.dumpFieldType(buf, field)
 		}
 
-		fmt.Fprintf(buf, " %s;\n", field.GetName())
+		fmt.Fprintf(buf, " %s;\n", m.name)
 		if i != 0 {
 			fields = fields + ","
 		}
-		fields = fields + field.GetName()
+		fields = fields + m.name
 	}
+	g.dumpHasBits(buf, msg, fieldTab)
 	fmt.Fprintf(buf, "\n%sKCFG_DEFINE_FIELDS(%s)\n", fieldTab, fields)
 
 	//constructor
-	fmt.Fprintf(buf, "\n%s%s(const mmdata::CharAllocator& alloc):", fieldTab, msg.GetName())
-	firstInitParam := true
-	for _, field := range msg.GetField() {
-		if g.isComplextType(field, false) {
-			if !firstInitParam {
-				fmt.Fprintf(buf, ",")
-			}
-			fmt.Fprintf(buf, "%s(alloc)", field.GetName())
-			firstInitParam = false
-		} else {
-			defaultInitVal, exist := g.withDefaultValue(field)
-			if exist {
-				if !firstInitParam {
-					fmt.Fprintf(buf, ",")
-				}
-				fmt.Fprintf(buf, "%s(%s)", field.GetName(), defaultInitVal)
-				firstInitParam = false
-			}
-		}
+	fmt.Fprintf(buf, "\n%s%s(const mmdata::CharAllocator& alloc)", fieldTab, g.structName(msg))
+	inits := g.memberInits(structMembers(msg))
+	if len(g.presenceFields(msg)) > 0 {
+		inits = append(inits, "has_bits_()")
+	}
+	if len(inits) > 0 {
+		fmt.Fprintf(buf, ":%s", strings.Join(inits, ","))
 	}
 	fmt.Fprintf(buf, "\n%s{}\n", fieldTab)
+	g.dumpPresenceAccessors(buf, msg, fieldTab)
+	g.dumpOneofAccessors(buf, msg, fieldTab)
 
 	//GetKey/GetValue
 	if haveKeyFiled {
-		fmt.Fprintf(buf, "\n%sconst key_type& GetKey() const { return %s; }\n", fieldTab, kv.Key.GetName())
-		fmt.Fprintf(buf, "%sconst value_type& GetValue() const { return %s; }\n", fieldTab, kv.Value.GetName())
+		if nil == kv.Key {
+			fmt.Fprintf(buf, "\n%skey_type GetKey() const { return key_type(%s); }\n", fieldTab, memberNames(keyMembers(kv.Keys)))
+		} else {
+			fmt.Fprintf(buf, "\n%sconst key_type& GetKey() const { return %s; }\n", fieldTab, kv.Key.GetName())
+		}
+		if nil == kv.Value {
+			fmt.Fprintf(buf, "%svalue_type GetValue() const { return value_type(%s); }\n", fieldTab, memberNames(valueMembers(msg, kv.Values)))
+		} else {
+			fmt.Fprintf(buf, "%sconst value_type& GetValue() const { return %s; }\n", fieldTab, kv.Value.GetName())
+		}
+	}
+
+	fmt.Fprintf(buf, "%s};\n", currentTAB)
+}
+
+// memberInits returns the initializers of members in a constructor taking
+// the allocator, like "name(alloc)".
+func (g *Generator) memberInits(members []member) []string {
+	var inits []string
+	for _, m := range members {
+		field := m.field
+		if m.oneof >= 0 || g.isComplextType(field, false) {
+			init := "alloc"
+			if m.oneof < 0 && !g.isComplextType(field, true) {
+				if v, exist := g.withDefaultValue(field); exist {
+					init = v + ", alloc"
+				}
+			}
+			inits = append(inits, fmt.Sprintf("%s(%s)", m.name, init))
+		} else if v, exist := g.withDefaultValue(field); exist {
+			inits = append(inits, fmt.Sprintf("%s(%s)", m.name, v))
+		}
 	}
+	return inits
+}
 
-	fmt.Fprintf(buf, "%s};\n\n", currentTAB)
+// dumpPrinter emits the operator<< of a message, in nested struct mode the
+// ones of the nested messages first.
+func (g *Generator) dumpPrinter(buf *bytes.Buffer, msg *descriptor.DescriptorProto, currentTAB string) {
+	if g.config.Nested != nestedMangle {
+		sorted, _ := g.sortNestedTypes(msg)
+		for _, nest := range sorted {
+			g.dumpPrinter(buf, nest, currentTAB)
+		}
+	}
+	g.dumpMembersPrinter(buf, g.messageName(msg), msg.GetName(), structMembers(msg), true, currentTAB)
+}
 
-	fmt.Fprintf(buf, "%sinline std::ostream& operator<<(std::ostream& os, const %s& v)\n", currentTAB, msg.GetName())
+// dumpMembersPrinter emits an operator<< printing members like
+// "[label:a=1,b=2]", the fields not set as null if presence.
+func (g *Generator) dumpMembersPrinter(buf *bytes.Buffer, typeName string, label string, members []member, presence bool, currentTAB string) {
+	fmt.Fprintf(buf, "%sinline std::ostream& operator<<(std::ostream& os, const %s& v)\n", currentTAB, typeName)
 	funcTab := currentTAB + "    "
 	fmt.Fprintf(buf, "%s{\n", currentTAB)
-	fmt.Fprintf(buf, "%sos<<\"[%s:\";\n", funcTab, msg.GetName())
-	for i, field := range msg.GetField() {
+	fmt.Fprintf(buf, "%sos<<\"[%s:\";\n", funcTab, label)
+	for i, m := range members {
+		sep := ""
 		if i > 0 {
-			fmt.Fprintf(buf, "%sos<<\",%s=\"<< v.%s;\n", funcTab, field.GetName(), field.GetName())
-		} else {
-			fmt.Fprintf(buf, "%sos<<\"%s=\"<< v.%s;\n", funcTab, field.GetName(), field.GetName())
+			sep = ","
+		}
+		value := "v." + m.name
+		if m.oneof < 0 && g.isCharType(m.field) && m.field.GetLabel() != descriptor.FieldDescriptorProto_LABEL_REPEATED {
+			//not as a character
+			value = "static_cast<int>(" + value + ")"
+		}
+		if m.oneof < 0 && g.isMapField(m.field) {
+			g.dumpMapPrinter(buf, m.field, m.name, sep, funcTab)
+			continue
 		}
+		//fields not set are printed as null
+		if presence && m.oneof < 0 && g.hasPresence(m.field) {
+			fmt.Fprintf(buf, "%sos<<\"%s%s=\";\n", funcTab, sep, m.name)
+			fmt.Fprintf(buf, "%sif(v.has_%s()) os<< %s;\n", funcTab, m.name, value)
+			fmt.Fprintf(buf, "%selse os<<\"null\";\n", funcTab)
+			continue
+		}
+		fmt.Fprintf(buf, "%sos<<\"%s%s=\"<< %s;\n", funcTab, sep, m.name, value)
 	}
 	fmt.Fprintf(buf, "%sos<<\"]\";\n", funcTab)
 	fmt.Fprintf(buf, "%sreturn os;\n", funcTab)
 	fmt.Fprintf(buf, "%s}\n\n", currentTAB)
+}
+
+func (g *Generator) dumpFieldType(buf *bytes.Buffer, field *descriptor.FieldDescriptorProto) {
+	fmt.Fprintf(buf, "%s", g.getFieldType(field))
+}
+
+// nestedTypes returns the nested messages to emit, map entries excluded.
+func nestedTypes(msg *descriptor.DescriptorProto) []*descriptor.DescriptorProto {
+	var nested []*descriptor.DescriptorProto
+	for _, nest := range msg.NestedType {
+		if !nest.GetOptions().GetMapEntry() {
+			nested = append(nested, nest)
+		}
+	}
+	return nested
+}
+
+// fileHasField returns whether a field of the messages of a file matches, the
+// fields of nested messages and map entries included.
+func fileHasField(file *descriptor.FileDescriptorProto, match func(field *descriptor.FieldDescriptorProto) bool) bool {
+	var walk func(msgs []*descriptor.DescriptorProto) bool
+	walk = func(msgs []*descriptor.DescriptorProto) bool {
+		for _, msg := range msgs {
+			for _, field := range msg.Field {
+				if match(field) {
+					return true
+				}
+			}
+			if walk(msg.NestedType) {
+				return true
+			}
+		}
+		return false
+	}
+	return walk(file.MessageType)
+}
+
+// messageName returns the C++ name of a message in its namespace, like
+// "Outer::Inner" for nested structs or "Outer_Inner" for mangled names.
+func (g *Generator) messageName(msg *descriptor.DescriptorProto) string {
+	t := g.registry.MessageOf(msg)
+	if nil == t {
+		return msg.GetName()
+	}
+	return g.localTypeName(t)
+}
+
+func (g *Generator) localTypeName(t *MessageType) string {
+	if g.config.Nested == nestedMangle {
+		return strings.Replace(t.LocalName(), ".", "_", -1)
+	}
+	return strings.Replace(t.LocalName(), ".", "::", -1)
+}
+
+// structName returns the name a message is declared with.
+func (g *Generator) structName(msg *descriptor.DescriptorProto) string {
+	if g.config.Nested == nestedMangle {
+		return g.messageName(msg)
+	}
+	return msg.GetName()
+}
+
+func (g *Generator) DumpMessage(msg *descriptor.DescriptorProto, currentTAB string) error {
+	buf := &g.OutputBuffer
+
+	msgOpts := g.messageOptions(msg)
+	kv, haveKeyFiled := g.hashEntryMessages[msg]
+	//a key type nested in the entry message is complete after the struct only,
+	//and so are the messages it holds and a composite key
+	keyHelpersAfter := false
+	owner := g.registry.MessageOf(msg)
+	dumpKeyHelpers := func() {
+		//the types of imported files have them from DumpImportedKeyHelpers
+		for _, t := range g.keyHelperTypes(kv.Keys...) {
+			keyType := g.cppTypeName(t.FullName)
+			if nil == owner || t.File != owner.File || g.isHashGened(keyType) {
+				continue
+			}
+			g.dumpKeyHelpers(buf, keyType, structMembers(t.Desc), false, currentTAB)
+			g.hashGened[keyType] = true
+		}
+		if nil == kv.Key {
+			g.dumpCompositeKeyHelpers(buf, msg, kv.Keys, currentTAB)
+		}
+	}
+	if haveKeyFiled {
+		keyHelpersAfter = nil == kv.Key
+		if g.config.Nested != nestedMangle && nil != owner {
+			for _, t := range g.keyHelperTypes(kv.Keys...) {
+				keyHelpersAfter = keyHelpersAfter || strings.HasPrefix(t.FullName, owner.FullName+".")
+			}
+		}
+		if !keyHelpersAfter {
+			dumpKeyHelpers()
+		}
+		fmt.Fprintf(buf, "%sstruct %sTable;\n", currentTAB, msg.GetName())
+	}
+
+	g.dumpStruct(buf, msg, currentTAB)
+	fmt.Fprintf(buf, "\n")
+	g.dumpPrinter(buf, msg, currentTAB)
+	if haveKeyFiled && nil == kv.Value {
+		g.dumpMembersPrinter(buf, msg.GetName()+"::value_type", msg.GetName()+".value", valueMembers(msg, kv.Values), false, currentTAB)
+	}
+	if haveKeyFiled && keyHelpersAfter {
+		dumpKeyHelpers()
+	}
 
 	if haveKeyFiled {
 		currentClass := fmt.Sprintf("%sTable", msg.GetName())
 		parentClassType := currentClass + "Parent"
-		parentClass := fmt.Sprintf("mmdata::SHMHashMap<%s, %s>::Type", g.getFieldType(kv.Key), g.getFieldType(kv.Value))
-		if rootMapType == "Tree" {
-			parentClass = fmt.Sprintf("mmdata::SHMMap<%s, %s>::Type", g.getFieldType(kv.Key), g.getFieldType(kv.Value))
+		keyType := msg.GetName() + "::key_type"
+		if nil != kv.Key {
+			keyType = g.getFieldType(kv.Key)
+		}
+		valueType := msg.GetName() + "::value_type"
+		if nil != kv.Value {
+			valueType = g.getFieldType(kv.Value)
+		}
+		multi := msgOpts.Table == TableMulti
+		parentClass := fmt.Sprintf("mmdata::SHMHashMap<%s, %s>::Type", keyType, valueType)
+		if multi {
+			parentClass = fmt.Sprintf("mmdata::SHMHashMultiMap<%s, %s>::Type", keyType, valueType)
+			if msgOpts.Container == ContainerTree {
+				parentClass = fmt.Sprintf("mmdata::SHMMultiMap<%s, %s>::Type", keyType, valueType)
+			}
+		} else if msgOpts.Container == ContainerTree {
+			parentClass = fmt.Sprintf("mmdata::SHMMap<%s, %s>::Type", keyType, valueType)
+		} else if msgOpts.Container == ContainerFlat {
+			parentClass = fmt.Sprintf("mmdata::pb::FlatTable<%s, %s>", keyType, valueType)
+			if msgOpts.Eytzinger {
+				parentClass = fmt.Sprintf("mmdata::pb::FlatTable<%s, %s, true>", keyType, valueType)
+			}
+		} else if msgOpts.Container == ContainerPerfectHash {
+			parentClass = fmt.Sprintf("mmdata::pb::PerfectHashTable<%s, %s>", keyType, valueType)
 		}
 		fmt.Fprintf(buf, "%stypedef %s %s;\n", currentTAB, parentClass, parentClassType)
 		fmt.Fprintf(buf, "\n%sstruct %s:public %s\n", currentTAB, currentClass, parentClassType)
 		fmt.Fprintf(buf, "%s{\n", currentTAB)
 		funcTab := currentTAB + "    "
-		fmt.Fprintf(buf, "%s%s(const mmdata::CharAllocator& alloc):%s(alloc)\n", funcTab, currentClass, parentClassType)
+		indexes := g.tableIndexes(kv)
+		inits := []string{parentClassType + "(alloc)"}
+		if len(indexes) > 0 {
+			g.dumpIndexMembers(buf, indexes, funcTab)
+			for _, index := range indexes {
+				inits = append(inits, index.name+"_index(alloc)")
+			}
+		}
+		fmt.Fprintf(buf, "%s%s(const mmdata::CharAllocator& alloc):%s\n", funcTab, currentClass, strings.Join(inits, ","))
 		fmt.Fprintf(buf, "%s{\n", funcTab)
 		fmt.Fprintf(buf, "%s}\n\n", funcTab)
 		hashData := g.NestMarshal(msg)
 		crcTable := crc64.MakeTable(123456789)
 		//g.HashValue = crc64.Checksum(hashData, crcTable)
 		fmt.Fprintf(buf, "%sstatic uint64_t GetHash() { return %dUL;} \n", funcTab, crc64.Checksum(hashData, crcTable))
-		fmt.Fprintf(buf, "%sbool Insert(const %s& entry) { return insert(value_type(entry.GetKey(), entry.GetValue())).second;} \n", funcTab, msg.GetName())
+		if multi {
+			//the entries with the same key are all kept
+			fmt.Fprintf(buf, "%sbool Insert(const %s& entry) { insert(value_type(entry.GetKey(), entry.GetValue())); return true;} \n", funcTab, msg.GetName())
+			fmt.Fprintf(buf, "%sstd::pair<const_iterator, const_iterator> EqualRange(const key_type& key) const { return equal_range(key);} \n", funcTab)
+			fmt.Fprintf(buf, "%sstd::pair<iterator, iterator> EqualRange(const key_type& key) { return equal_range(key);} \n", funcTab)
+		} else if len(indexes) > 0 {
+			g.dumpIndexInsert(buf, msg, indexes, funcTab)
+			g.dumpIndexFinders(buf, indexes, funcTab)
+		} else {
+			g.dumpInsert(buf, msg, kv, funcTab)
+		}
 		fmt.Fprintf(buf, "%s};\n", currentTAB)
 		//fmt.Fprintf(buf, "\n%stypedef mmdata::SHMHashMap<%s, %s>::Type %sTable;\n", currentTAB, g.getFieldType(keyField), g.getFieldType(valueField), msg.GetName())
 
 		builderClass := fmt.Sprintf("%sHelper", currentClass)
 		fmt.Fprintf(&g.CppBuffer, "%sstruct %s\n", currentTAB, builderClass)
 		fmt.Fprintf(&g.CppBuffer, "%s{\n", currentTAB)
-		fmt.Fprintf(&g.CppBuffer, "%sstatic int64_t Build(mmdata::DataImageBuildOptions& options, uint64_t& hash, std::string& err)\n", funcTab)
+		//BuildWith takes the builder as a template parameter for the tests
+		//to build the table with the builder of their own
+		fmt.Fprintf(&g.CppBuffer, "%stemplate<typename Builder>\n", funcTab)
+		fmt.Fprintf(&g.CppBuffer, "%sstatic int64_t BuildWith(Builder& builder, mmdata::DataImageBuildOptions& options, std::string& err)\n", funcTab)
 		fmt.Fprintf(&g.CppBuffer, "%s{\n", funcTab)
 		funcBodyTab := funcTab + "    "
-		fmt.Fprintf(&g.CppBuffer, "%shash = %s::GetHash();\n", funcBodyTab, currentClass)
-		fmt.Fprintf(&g.CppBuffer, "%smmdata::DataImageBuilder builder;\n", funcBodyTab)
-		fmt.Fprintf(&g.CppBuffer, "%sint64_t ret = builder.Build<%s>(options);\n", funcBodyTab, msg.GetName())
+		if g.needsBuildContext(msg) {
+			fmt.Fprintf(&g.CppBuffer, "%smmdata::pb::BuildContext ctx;\n", funcBodyTab)
+		}
+		if msgOpts.Container == ContainerPerfectHash {
+			g.dumpPerfectHashBuild(&g.CppBuffer, msg, funcBodyTab)
+		} else {
+			fmt.Fprintf(&g.CppBuffer, "%sint64_t ret = builder.template Build<%s>(options);\n", funcBodyTab, msg.GetName())
+		}
 		fmt.Fprintf(&g.CppBuffer, "%serr = builder.err;\n", funcBodyTab)
+		if msgOpts.Container == ContainerPerfectHash {
+			g.dumpPerfectHashReport(&g.CppBuffer, msg, funcBodyTab)
+		}
+		if g.hasUniqueIndex(indexes) {
+			g.dumpUniqueIndexReport(&g.CppBuffer, msg, funcBodyTab)
+		}
+		if !multi {
+			g.dumpDuplicateReport(&g.CppBuffer, msg, funcBodyTab)
+		}
 		fmt.Fprintf(&g.CppBuffer, "%sreturn ret;\n", funcBodyTab)
+		fmt.Fprintf(&g.CppBuffer, "%s}\n", funcTab)
+		fmt.Fprintf(&g.CppBuffer, "%sstatic int64_t Build(mmdata::DataImageBuildOptions& options, uint64_t& hash, std::string& err)\n", funcTab)
+		fmt.Fprintf(&g.CppBuffer, "%s{\n", funcTab)
+		fmt.Fprintf(&g.CppBuffer, "%shash = %s::GetHash();\n", funcBodyTab, currentClass)
+		fmt.Fprintf(&g.CppBuffer, "%smmdata::DataImageBuilder builder;\n", funcBodyTab)
+		fmt.Fprintf(&g.CppBuffer, "%sreturn BuildWith(builder, options, err);\n", funcBodyTab)
 		fmt.Fprintf(&g.CppBuffer, "%s}\n\n", funcTab)
 
 		fmt.Fprintf(&g.CppBuffer, "%sstatic int TestMemory(const void* mem, const std::string& json_key)\n", funcTab)
@@ -538,7 +1020,7 @@ func (g *Generator) DumpMessage(msg *descriptor.DescriptorProto, currentTAB stri
 		fmt.Fprintf(&g.CppBuffer, "%smmdata::MMData buf;\n", funcBodyTab)
 		fmt.Fprintf(&g.CppBuffer, "%sconst RootTable* root = buf.LoadRootReadObject<RootTable>(mem);\n", funcBodyTab)
 		fmt.Fprintf(&g.CppBuffer, "%sif (NULL == root) return -1;\n", funcBodyTab)
-		if g.isComplextType(kv.Key, false) {
+		if nil == kv.Key || g.isComplextType(kv.Key, false) {
 			fmt.Fprintf(&g.CppBuffer, "%smmdata::CharAllocator alloc;\n", funcBodyTab)
 			fmt.Fprintf(&g.CppBuffer, "%s%s::key_type key(alloc);\n", funcBodyTab, msg.GetName())
 		} else {
@@ -546,11 +1028,20 @@ func (g *Generator) DumpMessage(msg *descriptor.DescriptorProto, currentTAB stri
 		}
 
 		fmt.Fprintf(&g.CppBuffer, "%skcfg::Parse(d, \"\", key);\n", funcBodyTab)
-		fmt.Fprintf(&g.CppBuffer, "%sRootTable::const_iterator found = root->find(key);\n", funcBodyTab)
+		if multi {
+			fmt.Fprintf(&g.CppBuffer, "%sstd::pair<RootTable::const_iterator, RootTable::const_iterator> range = root->EqualRange(key);\n", funcBodyTab)
 
-		fmt.Fprintf(&g.CppBuffer, "%sif(found != root->end()){\n", funcBodyTab)
+			fmt.Fprintf(&g.CppBuffer, "%sif(range.first != range.second){\n", funcBodyTab)
+			fmt.Fprintf(&g.CppBuffer, "%sfor(RootTable::const_iterator found = range.first; found != range.second; ++found){\n", funcBodyTab2)
+			fmt.Fprintf(&g.CppBuffer, "%s    std::cout << \"Found entry \"<< found->first << \"->\" << found->second << std::endl;\n", funcBodyTab2)
+			fmt.Fprintf(&g.CppBuffer, "%s}\n", funcBodyTab2)
+		} else {
+			fmt.Fprintf(&g.CppBuffer, "%sRootTable::const_iterator found = root->find(key);\n", funcBodyTab)
+
+			fmt.Fprintf(&g.CppBuffer, "%sif(found != root->end()){\n", funcBodyTab)
 
-		fmt.Fprintf(&g.CppBuffer, "%sstd::cout << \"Found entry \"<< found->first << \"->\" << found->second << std::endl;\n", funcBodyTab2)
+			fmt.Fprintf(&g.CppBuffer, "%sstd::cout << \"Found entry \"<< found->first << \"->\" << found->second << std::endl;\n", funcBodyTab2)
+		}
 		fmt.Fprintf(&g.CppBuffer, "%sreturn 0;\n", funcBodyTab2)
 		fmt.Fprintf(&g.CppBuffer, "%s}\n", funcBodyTab)
 		fmt.Fprintf(&g.CppBuffer, "%sstd::cout << \"NO Entry found for jsno_key:\"<< json_key << \"&key_obj:\"<<key<<std::endl;\n", funcBodyTab)
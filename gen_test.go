@@ -0,0 +1,53 @@
+package main
+
+import (
+	"regexp"
+	"testing"
+)
+
+//go:generate protoc -Itestdata -I. --include_imports --include_source_info --descriptor_set_out=testdata/gethash2.pb testdata/gethash2.proto
+
+var getHashPattern = regexp.MustCompile(`struct (\w+):public \w+\n\s*\{\n(?:.*\n)*?\s*static uint64_t GetHash\(\) \{ return (\d+)UL;\}`)
+
+// firstGetHashes is the GetHash() of the tables of testdata/gethash.proto
+// given by the first releases, whose NestMarshal used the gogo/protobuf
+// marshaler with golang/protobuf v1.3.
+var firstGetHashes = map[string]string{
+	"WhiteListDataTable": "18446744073632687062",
+	"ScoresTable":        "18446744073603864439",
+}
+
+// testGetHashes returns the GetHash() of the tables of testdata/name.proto.
+func testGetHashes(t *testing.T, name string) map[string]string {
+	header := testGenerate(t, name, "")[name+".proto.hpp"]
+	hashes := make(map[string]string)
+	for _, match := range getHashPattern.FindAllStringSubmatch(header, -1) {
+		hashes[match[1]] = match[2]
+	}
+	return hashes
+}
+
+// TestGetHashUnchanged checks the GetHash() of tables of a schema with the
+// options of the first releases only against the values given by these
+// releases: a data image is only loaded by the code of the same hash, so a
+// change rejects all the images built.
+func TestGetHashUnchanged(t *testing.T) {
+	got := testGetHashes(t, "gethash")
+	for table, hash := range firstGetHashes {
+		if got[table] != hash {
+			t.Errorf("%s::GetHash() = %q, want %q", table, got[table], hash)
+		}
+	}
+}
+
+// TestGetHashHasBits checks that the tables of the proto2 version of
+// testdata/gethash.proto, whose descriptors are the same but whose messages
+// have has-bits, do not have the GetHash() of the proto3 ones.
+func TestGetHashHasBits(t *testing.T) {
+	got := testGetHashes(t, "gethash2")
+	for table, hash := range firstGetHashes {
+		if len(got[table]) == 0 || got[table] == hash {
+			t.Errorf("%s::GetHash() = %q with has-bits, want another one than %q", table, got[table], hash)
+		}
+	}
+}
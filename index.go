@@ -0,0 +1,297 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// tableIndex is a secondary index of a root table, on a field of the value
+// with (mmdata.index).
+type tableIndex struct {
+	name   string
+	field  *descriptor.FieldDescriptorProto
+	unique bool
+	//the field in an entry, like "entry.value.name"
+	value string
+	//the condition to index an entry, for fields with presence
+	has string
+}
+
+var indexNamePattern = regexp.MustCompile(`^[A-Za-z_]\w*$`)
+
+// tableIndexes returns the secondary indexes of the root table of an entry
+// message, on the fields of its value message or of its composite value.
+func (g *Generator) tableIndexes(kv KeyValueFiled) []tableIndex {
+	fields := kv.Values
+	prefix := "entry."
+	if nil != kv.Value {
+		fields = nil
+		if t := g.heldMessage(kv.Value); nil != t && kv.Value.GetLabel() != descriptor.FieldDescriptorProto_LABEL_REPEATED {
+			fields = t.Desc.Field
+			prefix = "entry." + kv.Value.GetName() + "."
+		}
+	}
+	var indexes []tableIndex
+	for _, field := range fields {
+		opts := g.fieldOptions(field)
+		if len(opts.Index) == 0 {
+			continue
+		}
+		index := tableIndex{name: opts.Index, field: field, unique: opts.UniqueIndex, value: prefix + field.GetName()}
+		if g.hasPresence(field) {
+			index.has = prefix + "has_" + field.GetName() + "()"
+		}
+		indexes = append(indexes, index)
+	}
+	return indexes
+}
+
+// verifyIndexes reports the invalid (mmdata.index) of the value fields of a
+// root table.
+func (g *Generator) verifyIndexes(msg *descriptor.DescriptorProto, kv KeyValueFiled) {
+	for _, field := range msg.Field {
+		if len(g.fieldOptions(field).Index) == 0 {
+			continue
+		}
+		if g.fieldOptions(field).Key {
+			g.fieldError(field, "Option (mmdata.index) is not supported for key fields")
+		} else if field == kv.Value {
+			g.fieldError(field, "Option (mmdata.index) is not supported for the (Value) field, set it on the fields of the value message")
+		}
+	}
+	names := make(map[string]bool)
+	for _, index := range g.tableIndexes(kv) {
+		field := index.field
+		if g.messageOptions(msg).Table == TableMulti {
+			g.fieldError(field, "Option (mmdata.index) is not supported for tables with (mmdata.table) = MULTI")
+			continue
+		}
+		if !indexNamePattern.MatchString(index.name) {
+			g.fieldError(field, "Invalid option (mmdata.index):%q is not a C++ identifier", index.name)
+			continue
+		}
+		if names[index.name] {
+			g.fieldError(field, "Duplicate option (mmdata.index) = %q in table %s", index.name, msg.GetName())
+			continue
+		}
+		names[index.name] = true
+		if field.GetLabel() == descriptor.FieldDescriptorProto_LABEL_REPEATED || isOneofField(field) {
+			g.fieldError(field, "Option (mmdata.index) is not supported for repeated or oneof fields")
+			continue
+		}
+		if nil != g.heldMessage(field) {
+			g.fieldError(field, "Option (mmdata.index) is not supported for message fields")
+		}
+	}
+}
+
+// fileHasMultiIndex returns whether a root table of a file has a multi
+// index, whose FindByName() returns a std::vector.
+func (g *Generator) fileHasMultiIndex(file *descriptor.FileDescriptorProto) bool {
+	for _, msg := range file.MessageType {
+		kv, haveKeyFiled := g.hashEntryMessages[msg]
+		if !haveKeyFiled {
+			continue
+		}
+		for _, index := range g.tableIndexes(kv) {
+			if !index.unique {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// dumpIndexMembers emits the index containers of a root table, mapping the
+// indexed values to the keys of the entries.
+func (g *Generator) dumpIndexMembers(buf *bytes.Buffer, indexes []tableIndex, currentTAB string) {
+	for _, index := range indexes {
+		keys := "key_type"
+		if !index.unique {
+			keys = "mmdata::SHMVector<key_type>::Type"
+		}
+		fmt.Fprintf(buf, "%stypedef mmdata::SHMHashMap<%s, %s>::Type %sIndex;\n", currentTAB, g.getFieldType(index.field), keys, camelCase(index.name))
+		fmt.Fprintf(buf, "%s%sIndex %s_index;\n", currentTAB, camelCase(index.name), index.name)
+	}
+	fmt.Fprintf(buf, "\n")
+}
+
+// dumpIndexInsert emits the Insert of a root table with indexes, which fails
+// for an entry with the value of a unique index already present, counted in
+// the BuildContext of the build.
+func (g *Generator) dumpIndexInsert(buf *bytes.Buffer, msg *descriptor.DescriptorProto, indexes []tableIndex, currentTAB string) {
+	funcTab := currentTAB + "    "
+	fmt.Fprintf(buf, "%sbool Insert(const %s& entry)\n", currentTAB, msg.GetName())
+	fmt.Fprintf(buf, "%s{\n", currentTAB)
+	g.dumpPerfectHashInsert(buf, msg, funcTab)
+	for _, index := range indexes {
+		if !index.unique {
+			continue
+		}
+		cond := fmt.Sprintf("%s_index.find(%s) != %s_index.end()", index.name, index.value, index.name)
+		if len(index.has) > 0 {
+			cond = index.has + " && " + cond
+		}
+		fmt.Fprintf(buf, "%sif(%s)\n", funcTab, cond)
+		fmt.Fprintf(buf, "%s{\n", funcTab)
+		fmt.Fprintf(buf, "%s    mmdata::pb::BuildContext::CountUniqueIndexConflict();\n", funcTab)
+		fmt.Fprintf(buf, "%s    return false;\n", funcTab)
+		fmt.Fprintf(buf, "%s}\n", funcTab)
+	}
+	fmt.Fprintf(buf, "%skey_type key = entry.GetKey();\n", funcTab)
+	fmt.Fprintf(buf, "%sif(!insert(value_type(key, entry.GetValue())).second)\n", funcTab)
+	fmt.Fprintf(buf, "%s{\n", funcTab)
+	fmt.Fprintf(buf, "%s    mmdata::pb::BuildContext::CountDuplicateKey();\n", funcTab)
+	fmt.Fprintf(buf, "%s    return false;\n", funcTab)
+	fmt.Fprintf(buf, "%s}\n", funcTab)
+	for _, index := range indexes {
+		if index.unique {
+			if len(index.has) > 0 {
+				fmt.Fprintf(buf, "%sif(%s) ", funcTab, index.has)
+			} else {
+				fmt.Fprintf(buf, "%s", funcTab)
+			}
+			fmt.Fprintf(buf, "%s_index.insert(std::make_pair(%s, key));\n", index.name, index.value)
+			continue
+		}
+		//a block for the iterator of each index
+		if len(index.has) > 0 {
+			fmt.Fprintf(buf, "%sif(%s)\n", funcTab, index.has)
+		}
+		fmt.Fprintf(buf, "%s{\n", funcTab)
+		tab := funcTab + "    "
+		fmt.Fprintf(buf, "%s%sIndex::iterator found = %s_index.find(%s);\n", tab, camelCase(index.name), index.name, index.value)
+		fmt.Fprintf(buf, "%sif(found == %s_index.end())\n", tab, index.name)
+		fmt.Fprintf(buf, "%s{\n", tab)
+		fmt.Fprintf(buf, "%s    found = %s_index.insert(std::make_pair(%s, mmdata::SHMVector<key_type>::Type(mmdata::CharAllocator(get_allocator())))).first;\n", tab, index.name, index.value)
+		fmt.Fprintf(buf, "%s}\n", tab)
+		fmt.Fprintf(buf, "%sfound->second.push_back(key);\n", tab)
+		fmt.Fprintf(buf, "%s}\n", funcTab)
+	}
+	fmt.Fprintf(buf, "%sreturn true;\n", funcTab)
+	fmt.Fprintf(buf, "%s}\n", currentTAB)
+}
+
+// dumpIndexFinders emits the FindByName() of the indexes of a root table, the
+// entry of a unique index or end(), and all the entries of a multi index. The
+// indexes of SHMString fields are also found by std::string and const char*,
+// with no SHMString to allocate.
+func (g *Generator) dumpIndexFinders(buf *bytes.Buffer, indexes []tableIndex, currentTAB string) {
+	for _, index := range indexes {
+		g.dumpIndexFinder(buf, index, g.getFieldType(index.field), index.name+"_index.find(v)", currentTAB)
+		if !g.isStringIndex(index) {
+			continue
+		}
+		g.dumpIndexFinder(buf, index, "std::string", index.name+"_index.find(v, mmdata::pb::StringHash(), mmdata::pb::StringEqual())", currentTAB)
+		result := "const_iterator"
+		if !index.unique {
+			result = "std::vector<const_iterator>"
+		}
+		fmt.Fprintf(buf, "%s%s FindBy%s(const char* v) const { return FindBy%s(std::string(v));} \n", currentTAB, result, camelCase(index.name), camelCase(index.name))
+	}
+}
+
+// dumpIndexFinder emits the FindByName() of an index taking a value of
+// argType, found in the index with find.
+func (g *Generator) dumpIndexFinder(buf *bytes.Buffer, index tableIndex, argType string, find string, currentTAB string) {
+	funcTab := currentTAB + "    "
+	name := camelCase(index.name)
+	if index.unique {
+		fmt.Fprintf(buf, "%sconst_iterator FindBy%s(const %s& v) const\n", currentTAB, name, argType)
+		fmt.Fprintf(buf, "%s{\n", currentTAB)
+		fmt.Fprintf(buf, "%s%sIndex::const_iterator found = %s;\n", funcTab, name, find)
+		fmt.Fprintf(buf, "%sif(found == %s_index.end()) return end();\n", funcTab, index.name)
+		fmt.Fprintf(buf, "%sreturn find(found->second);\n", funcTab)
+		fmt.Fprintf(buf, "%s}\n", currentTAB)
+		return
+	}
+	fmt.Fprintf(buf, "%sstd::vector<const_iterator> FindBy%s(const %s& v) const\n", currentTAB, name, argType)
+	fmt.Fprintf(buf, "%s{\n", currentTAB)
+	fmt.Fprintf(buf, "%sstd::vector<const_iterator> entries;\n", funcTab)
+	fmt.Fprintf(buf, "%s%sIndex::const_iterator found = %s;\n", funcTab, name, find)
+	fmt.Fprintf(buf, "%sif(found == %s_index.end()) return entries;\n", funcTab, index.name)
+	fmt.Fprintf(buf, "%sfor(size_t i = 0; i < found->second.size(); i++)\n", funcTab)
+	fmt.Fprintf(buf, "%s{\n", funcTab)
+	fmt.Fprintf(buf, "%s    entries.push_back(find(found->second[i]));\n", funcTab)
+	fmt.Fprintf(buf, "%s}\n", funcTab)
+	fmt.Fprintf(buf, "%sreturn entries;\n", funcTab)
+	fmt.Fprintf(buf, "%s}\n", currentTAB)
+}
+
+// dumpUniqueIndexReport emits the report to err of the entries dropped by the
+// Build of a root table, counted in ctx, for a value of a unique index already
+// inserted, failing the build with (mmdata.duplicate_key) = FAIL_BUILD.
+func (g *Generator) dumpUniqueIndexReport(buf *bytes.Buffer, msg *descriptor.DescriptorProto, currentTAB string) {
+	fmt.Fprintf(buf, "%sif(ctx.unique_index_conflicts > 0)\n", currentTAB)
+	fmt.Fprintf(buf, "%s{\n", currentTAB)
+	fmt.Fprintf(buf, "%s    std::ostringstream report;\n", currentTAB)
+	fmt.Fprintf(buf, "%s    if(!err.empty()) report<<\"; \";\n", currentTAB)
+	fmt.Fprintf(buf, "%s    report<<ctx.unique_index_conflicts<<\" entries dropped in %s for a value of a (mmdata.unique_index) already inserted\";\n", currentTAB, msg.GetName())
+	fmt.Fprintf(buf, "%s    err += report.str();\n", currentTAB)
+	if g.messageOptions(msg).DuplicateKey == DuplicateFailBuild {
+		fmt.Fprintf(buf, "%s    ret = -1;\n", currentTAB)
+	}
+	fmt.Fprintf(buf, "%s}\n", currentTAB)
+}
+
+// isStringIndex returns whether an index is on a SHMString field.
+func (g *Generator) isStringIndex(index tableIndex) bool {
+	return g.getFieldType(index.field) == "mmdata::SHMString"
+}
+
+// hasUniqueIndex returns whether some of the indexes of a root table are
+// unique ones.
+func (g *Generator) hasUniqueIndex(indexes []tableIndex) bool {
+	for _, index := range indexes {
+		if index.unique {
+			return true
+		}
+	}
+	return false
+}
+
+// DumpIndexTypes emits the functors finding the SHMString keys of the indexes
+// by std::string if a root table of the file has an index on a SHMString field.
+func (g *Generator) DumpIndexTypes(file *descriptor.FileDescriptorProto) {
+	for _, msg := range file.MessageType {
+		kv, haveKeyFiled := g.hashEntryMessages[msg]
+		if !haveKeyFiled {
+			continue
+		}
+		for _, index := range g.tableIndexes(kv) {
+			if g.isStringIndex(index) {
+				fmt.Fprintf(&g.OutputBuffer, "%s\n", indexTypesCode)
+				return
+			}
+		}
+	}
+}
+
+// indexTypesCode holds the hash and equality of the strings of any type, the
+// same for SHMString and std::string, to find the SHMString keys of an index
+// by a std::string.
+const indexTypesCode = `#ifndef MMDATA_INDEX_TYPES_
+#define MMDATA_INDEX_TYPES_
+#include <algorithm>
+#include <string>
+namespace mmdata
+{
+    namespace pb
+    {
+        struct StringHash
+        {
+            template<typename S>
+            std::size_t operator()(const S& v) const { return boost::hash_range(v.begin(), v.end()); }
+        };
+        struct StringEqual
+        {
+            template<typename A, typename B>
+            bool operator()(const A& a, const B& b) const { return a.size() == b.size() && std::equal(a.begin(), a.end(), b.begin()); }
+        };
+    }
+}
+#endif /* MMDATA_INDEX_TYPES_ */
+`
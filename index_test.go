@@ -0,0 +1,45 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+//go:generate protoc -Itestdata -I. --include_imports --include_source_info --descriptor_set_out=testdata/indexes.pb testdata/indexes.proto
+//go:generate protoc -Itestdata -I. --include_imports --include_source_info --descriptor_set_out=testdata/indexes_errors.pb testdata/indexes_errors.proto
+
+func TestIndexes(t *testing.T) {
+	files := testGenerate(t, "indexes", "")
+	header := files["indexes.proto.hpp"]
+	for _, want := range []string{
+		"#include <vector>\n",
+		"typedef mmdata::SHMHashMap<mmdata::SHMString, key_type>::Type TitleIndex;",
+		"typedef mmdata::SHMHashMap<int32_t, mmdata::SHMVector<key_type>::Type>::Type CategoryIndex;",
+		"ItemDataTable(const mmdata::CharAllocator& alloc):ItemDataTableParent(alloc),title_index(alloc),category_index(alloc),shop_id_index(alloc)",
+		"if(entry.item.has_title() && title_index.find(entry.item.title) != title_index.end())\n                {\n                    mmdata::pb::BuildContext::CountUniqueIndexConflict();\n                    return false;\n                }\n",
+		"const_iterator FindByTitle(const std::string& v) const",
+		"std::vector<const_iterator> FindByShopId(const int32_t& v) const",
+		"static thread_local BuildContext* current = NULL;",
+	} {
+		if !strings.Contains(header, want) {
+			t.Errorf("indexes.proto.hpp does not contain %q:\n%s", want, header)
+		}
+	}
+	source := files["indexes.proto.cpp"]
+	for _, want := range []string{
+		"mmdata::pb::BuildContext ctx;\n                int64_t ret = builder.template Build<ItemData>(options);",
+		"report<<ctx.unique_index_conflicts<<\" entries dropped in ItemData for a value of a (mmdata.unique_index) already inserted\";",
+	} {
+		if !strings.Contains(source, want) {
+			t.Errorf("indexes.proto.cpp does not contain %q:\n%s", want, source)
+		}
+	}
+	//Stat has no unique index to count conflicts of
+	if strings.Contains(source, "entries dropped in Stat") {
+		t.Errorf("indexes.proto.cpp reports unique index conflicts of Stat:\n%s", source)
+	}
+}
+
+func TestIndexesBuild(t *testing.T) {
+	testCppChecks(t, "indexes")
+}
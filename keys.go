@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// sortKeys puts the [(Key) = true] fields of a message in key order, by
+// (mmdata.key_order) if given for all of them, or in field order.
+func (g *Generator) sortKeys(msg *descriptor.DescriptorProto, keys []*descriptor.FieldDescriptorProto) {
+	ordered := 0
+	for _, key := range keys {
+		if g.fieldOptions(key).HasKeyOrder {
+			ordered++
+		}
+	}
+	if ordered == 0 {
+		return
+	}
+	if ordered != len(keys) {
+		g.messageError(msg, "Option (mmdata.key_order) is missing on some fields with [(Key) = true]")
+		return
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		return g.fieldOptions(keys[i]).KeyOrder < g.fieldOptions(keys[j]).KeyOrder
+	})
+	for i := 1; i < len(keys); i++ {
+		if order := g.fieldOptions(keys[i]).KeyOrder; order == g.fieldOptions(keys[i-1]).KeyOrder {
+			g.fieldError(keys[i], "Duplicate option (mmdata.key_order) = %d", order)
+		}
+	}
+	for _, key := range keys {
+		if g.isMapField(key) && g.fieldOptions(key).Container == ContainerHash {
+			g.fieldError(key, "Option (mmdata.map_container) = HASH is not supported in composite keys, use TREE or FLAT_SORTED")
+		}
+	}
+}
+
+func keyMembers(keys []*descriptor.FieldDescriptorProto) []member {
+	var members []member
+	for _, key := range keys {
+		members = append(members, member{name: key.GetName(), field: key, oneof: -1})
+	}
+	return members
+}
+
+// valueMembers returns the members of the value fields of an entry message
+// in field order, the oneofs of the fields included.
+func valueMembers(msg *descriptor.DescriptorProto, values []*descriptor.FieldDescriptorProto) []member {
+	isValue := make(map[*descriptor.FieldDescriptorProto]bool)
+	for _, value := range values {
+		isValue[value] = true
+	}
+	var members []member
+	for _, m := range structMembers(msg) {
+		if m.oneof < 0 && isValue[m.field] {
+			members = append(members, m)
+			continue
+		}
+		for _, field := range oneofFields(msg, m.oneof) {
+			if isValue[field] {
+				members = append(members, m)
+				break
+			}
+		}
+	}
+	return members
+}
+
+func memberNames(members []member) string {
+	var names []string
+	for _, m := range members {
+		names = append(names, m.name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// dumpMembersStruct emits a struct of copies of members of a message, the
+// key_type or value_type of an entry message with several key or value
+// fields. It is parsed from a json object with the names of the members.
+func (g *Generator) dumpMembersStruct(buf *bytes.Buffer, msg *descriptor.DescriptorProto, name string, members []member, currentTAB string) {
+	fieldTab := currentTAB + "    "
+	fmt.Fprintf(buf, "%sstruct %s\n", currentTAB, name)
+	fmt.Fprintf(buf, "%s{\n", currentTAB)
+	var names, params, copies []string
+	for _, m := range members {
+		t := g.getFieldType(m.field)
+		if m.oneof >= 0 {
+			//the typedef of the union in the message
+			t = oneofLocalName(msg.OneofDecl[m.oneof])
+		}
+		fmt.Fprintf(buf, "%s%s %s;\n", fieldTab, t, m.name)
+		names = append(names, m.name)
+		params = append(params, fmt.Sprintf("const %s& %s", t, m.name))
+		copies = append(copies, fmt.Sprintf("%s(%s)", m.name, m.name))
+	}
+	fmt.Fprintf(buf, "\n%sKCFG_DEFINE_FIELDS(%s)\n", fieldTab, strings.Join(names, ","))
+	fmt.Fprintf(buf, "\n%s%s(const mmdata::CharAllocator& alloc)", fieldTab, name)
+	if inits := g.memberInits(members); len(inits) > 0 {
+		fmt.Fprintf(buf, ":%s", strings.Join(inits, ","))
+	}
+	fmt.Fprintf(buf, "\n%s{}\n", fieldTab)
+	fmt.Fprintf(buf, "%s%s(%s):%s\n", fieldTab, name, strings.Join(params, ", "), strings.Join(copies, ","))
+	fmt.Fprintf(buf, "%s{}\n", fieldTab)
+	fmt.Fprintf(buf, "%s};\n", currentTAB)
+}
+
+// dumpCompositeKeyHelpers emits the key helpers and the operator<< of the
+// composite key of an entry message, after the entry struct.
+func (g *Generator) dumpCompositeKeyHelpers(buf *bytes.Buffer, msg *descriptor.DescriptorProto, keys []*descriptor.FieldDescriptorProto, currentTAB string) {
+	keyType := msg.GetName() + "::key_type"
+	g.dumpKeyHelpers(buf, keyType, keyMembers(keys), true, currentTAB)
+	g.dumpMembersPrinter(buf, keyType, msg.GetName()+".key", keyMembers(keys), false, currentTAB)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+//go:generate protoc -Itestdata -I. --include_imports --include_source_info --descriptor_set_out=testdata/keys_errors.pb testdata/keys_errors.proto
+
+// testKey returns a [(Key) = true] field, with (mmdata.key_order) = order if
+// order is not negative.
+func testKey(name string, number int32, order int) *descriptor.FieldDescriptorProto {
+	field := testField(name, number, "", false)
+	field.Options = &descriptor.FieldOptions{}
+	proto.SetExtension(field.Options, E_Key, proto.Bool(true))
+	if order >= 0 {
+		proto.SetExtension(field.Options, E_Mmdata_KeyOrder, proto.Uint32(uint32(order)))
+	}
+	return field
+}
+
+func TestSortKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		keys []*descriptor.FieldDescriptorProto
+		want []string
+		err  string
+	}{
+		{
+			name: "field order",
+			keys: []*descriptor.FieldDescriptorProto{testKey("b", 1, -1), testKey("a", 2, -1)},
+			want: []string{"b", "a"},
+		},
+		{
+			name: "key order",
+			keys: []*descriptor.FieldDescriptorProto{testKey("b", 1, 2), testKey("a", 2, 1), testKey("c", 3, 0)},
+			want: []string{"c", "a", "b"},
+		},
+		{
+			name: "missing key order",
+			keys: []*descriptor.FieldDescriptorProto{testKey("b", 1, 1), testKey("a", 2, -1)},
+			want: []string{"b", "a"},
+			err:  "test.proto: demo.M: Option (mmdata.key_order) is missing on some fields with [(Key) = true]",
+		},
+		{
+			name: "duplicate key order",
+			keys: []*descriptor.FieldDescriptorProto{testKey("b", 1, 1), testKey("a", 2, 1)},
+			want: []string{"b", "a"},
+			err:  "test.proto: demo.M.a: Duplicate option (mmdata.key_order) = 1",
+		},
+	}
+	for _, test := range tests {
+		msg := testMessage("M", test.keys)
+		g, _ := testGenerator(DefaultConfig(), msg)
+		keys := append([]*descriptor.FieldDescriptorProto{}, test.keys...)
+		g.sortKeys(msg, keys)
+		var got []string
+		for _, key := range keys {
+			got = append(got, key.GetName())
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: keys %v, want %v", test.name, got, test.want)
+		}
+		if got := g.diag.Error(); got != test.err {
+			t.Errorf("%s: error %q, want %q", test.name, got, test.err)
+		}
+	}
+}
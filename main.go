@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
@@ -16,8 +17,7 @@ func main() {
 		log.Fatalf("reading input:%v", err)
 	}
 
-	var request plugin.CodeGeneratorRequest   // The input.
-	var response plugin.CodeGeneratorResponse // The output.
+	var request plugin.CodeGeneratorRequest // The input.
 	if err := proto.Unmarshal(data, &request); err != nil {
 		log.Fatalf("parsing input proto:%v", err)
 	}
@@ -26,18 +26,57 @@ func main() {
 		log.Fatalf("no files to generate")
 	}
 
+	response := process(&request) // The output.
+	rdata, _ := proto.Marshal(response)
+	os.Stdout.Write(rdata)
+	//log.Printf("\n%s", g.OutputBuffer.String())
+}
+
+// process generates the files of a request, or the error of all the problems
+// found in them.
+func process(request *plugin.CodeGeneratorRequest) *plugin.CodeGeneratorResponse {
+	response := &plugin.CodeGeneratorResponse{}
+	registry := NewRegistry(request.ProtoFile)
+	diag := &Diagnostics{}
+	generate := make(map[string]bool)
+	for _, name := range request.FileToGenerate {
+		generate[name] = true
+	}
+	config, err := ParseConfig(request.GetParameter())
+	if err != nil {
+		diag.Add(&Diagnostic{Text: err.Error()})
+		generate = nil
+	}
+
+	outputs := make(map[string]string)
 	for _, file := range request.ProtoFile {
-		g := &Generator{}
+		if !generate[file.GetName()] {
+			continue
+		}
+		g := &Generator{config: config, registry: registry, diag: diag}
 		if !g.Verify(file) {
 			continue
 		}
-		g.BuildTypeNameMap(file)
-		g.DumpHeader(file.GetName())
-		tab, tabs := g.DumpNamespaceBegin(*file.Package)
-
-		for _, msg := range file.MessageType {
-			g.DumpMessage(msg, tab)
+		g.DumpHeader(file)
+		if other, exist := outputs[g.dumpFileName]; exist {
+			diag.Add(&Diagnostic{File: file.GetName(), Text: fmt.Sprintf("output %s conflicts with the one of %s, use paths=source_relative or paths=import", g.dumpFileName, other)})
+			continue
 		}
+		outputs[g.dumpFileName] = file.GetName()
+		g.DumpImports(file)
+		g.DumpWellKnownTypes(file)
+		g.DumpFixedTypes(file)
+		g.DumpBytesTypes(file)
+		g.DumpBuildContextTypes(file)
+		g.DumpIndexTypes(file)
+		g.DumpFlatTableTypes(file)
+		g.DumpPerfectHashTypes(file)
+		g.DumpImportedKeyHelpers(file)
+		g.DumpEnums(file)
+		g.DumpOneofs(file)
+		tab, tabs := g.DumpNamespaceBegin(file.GetPackage())
+
+		g.DumpMessages(file, tab)
 		g.DumpNamespaceEnd(tabs)
 		g.Finish()
 		//g.DumpFile()
@@ -46,12 +85,17 @@ func main() {
 		f.Content = proto.String(g.OutputBuffer.String())
 		response.File = append(response.File, f)
 
-		sf := &plugin.CodeGeneratorResponse_File{}
-		sf.Name = proto.String(g.dumpCppName)
-		sf.Content = proto.String(g.CppBuffer.String())
-		response.File = append(response.File, sf)
+		if config.EmitCpp {
+			sf := &plugin.CodeGeneratorResponse_File{}
+			sf.Name = proto.String(g.dumpCppName)
+			sf.Content = proto.String(g.CppBuffer.String())
+			response.File = append(response.File, sf)
+		}
 	}
-	rdata, _ := proto.Marshal(&response)
-	os.Stdout.Write(rdata)
-	//log.Printf("\n%s", g.OutputBuffer.String())
+	response.SupportedFeatures = proto.Uint64(uint64(plugin.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL))
+	if diag.Len() > 0 {
+		response.File = nil
+		response.Error = proto.String(diag.Error())
+	}
+	return response
 }
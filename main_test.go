@@ -0,0 +1,250 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	plugin "github.com/golang/protobuf/protoc-gen-go/plugin"
+)
+
+// The descriptor sets of the test schemas, given to process like protoc does.
+//go:generate protoc -Itestdata -I. --include_imports --include_source_info --descriptor_set_out=testdata/gethash.pb testdata/gethash.proto
+//go:generate protoc -Itestdata -I. --include_imports --include_source_info --descriptor_set_out=testdata/imports.pb testdata/imports.proto
+//go:generate protoc -Itestdata -I. --include_imports --include_source_info --descriptor_set_out=testdata/errors.pb testdata/errors.proto
+//go:generate protoc -Itestdata -I. --include_imports --include_source_info --descriptor_set_out=testdata/layout.pb testdata/a/data.proto testdata/b/data.proto
+//go:generate protoc -Itestdata -I. --include_imports --include_source_info --descriptor_set_out=testdata/cycle.pb testdata/cycle.proto
+//go:generate protoc -Itestdata -I. --include_imports --include_source_info --descriptor_set_out=testdata/defaults_errors.pb testdata/defaults_errors.proto
+
+// testRequest returns the request generating testdata/name.proto, or the
+// given files, from the descriptor set of them and their imports in
+// testdata/name.pb.
+func testRequest(t *testing.T, name string, param string, files ...string) *plugin.CodeGeneratorRequest {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", name+".pb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var set descriptor.FileDescriptorSet
+	if err := proto.Unmarshal(data, &set); err != nil {
+		t.Fatalf("parsing %s.pb:%v", name, err)
+	}
+	if len(files) == 0 {
+		files = []string{name + ".proto"}
+	}
+	request := &plugin.CodeGeneratorRequest{FileToGenerate: files, ProtoFile: set.File}
+	if len(param) > 0 {
+		request.Parameter = proto.String(param)
+	}
+	return request
+}
+
+// testGenerate returns the files generated for testdata/name.proto, or the
+// given files, by name.
+func testGenerate(t *testing.T, name string, param string, protos ...string) map[string]string {
+	response := process(testRequest(t, name, param, protos...))
+	if nil != response.Error {
+		t.Fatalf("generating %s.proto:%s", name, response.GetError())
+	}
+	files := make(map[string]string)
+	for _, f := range response.File {
+		files[f.GetName()] = f.GetContent()
+	}
+	return files
+}
+
+func TestProcessFileToGenerate(t *testing.T) {
+	files := testGenerate(t, "imports", "")
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	if len(files) != 2 || len(files["imports.proto.hpp"]) == 0 || len(files["imports.proto.cpp"]) == 0 {
+		t.Fatalf("generated %v, want imports.proto.hpp and imports.proto.cpp only", names)
+	}
+	if header := files["imports.proto.hpp"]; !strings.Contains(header, "#include \"common.proto.hpp\"\n") {
+		t.Errorf("imports.proto.hpp does not include common.proto.hpp:\n%s", header)
+	} else if strings.Contains(header, "struct Item") {
+		t.Errorf("imports.proto.hpp defines Item of common.proto:\n%s", header)
+	}
+}
+
+func TestProcessImportedTypes(t *testing.T) {
+	header := testGenerate(t, "imports", "")["imports.proto.hpp"]
+	for _, want := range []string{
+		"typedef ::test::common::Item key_type;",
+		"typedef mmdata::SHMVector<::test::common::Item>::Type value_type;",
+		"#ifndef MMDATA_KEY_HELPERS_TEST_COMMON_ITEM_\n",
+		"inline std::size_t hash_value(const ::test::common::Item& v)",
+	} {
+		if !strings.Contains(header, want) {
+			t.Errorf("imports.proto.hpp does not contain %q:\n%s", want, header)
+		}
+	}
+}
+
+func TestProcessConfig(t *testing.T) {
+	files := testGenerate(t, "imports", "header_ext=.h,emit_cpp=false,include_prefix=mmdata,namespace_prefix=shm")
+	header, ok := files["imports.proto.h"]
+	if len(files) != 1 || !ok {
+		t.Fatalf("generated %d files, want imports.proto.h only", len(files))
+	}
+	for _, want := range []string{
+		"#include \"mmdata/mmdata.hpp\"\n",
+		"#include \"common.proto.h\"\n",
+		"namespace shm\n{\n    namespace test\n    {\n        namespace imports\n",
+		"typedef ::shm::test::common::Item key_type;",
+	} {
+		if !strings.Contains(header, want) {
+			t.Errorf("imports.proto.h does not contain %q:\n%s", want, header)
+		}
+	}
+}
+
+func TestProcessPaths(t *testing.T) {
+	for _, test := range []struct {
+		param            string
+		header, included string
+	}{
+		{"", "b/data.proto.hpp", "example.com/test/a/data.proto.hpp"},
+		{"paths=import", "b/data.proto.hpp", "example.com/test/a/data.proto.hpp"},
+		{"paths=source_relative", "b/data.proto.hpp", "a/data.proto.hpp"},
+	} {
+		files := testGenerate(t, "layout", test.param, "a/data.proto", "b/data.proto")
+		if len(files[test.included]) == 0 {
+			t.Errorf("%q generated no %s", test.param, test.included)
+		}
+		if header := files[test.header]; !strings.Contains(header, "#include \""+test.included+"\"\n") {
+			t.Errorf("%q generated %s without including %s:\n%s", test.param, test.header, test.included, header)
+		}
+	}
+}
+
+// TestProcessErrors checks that the problems of a schema are all reported,
+// one per line, through CodeGeneratorResponse.Error without any file.
+func TestProcessErrors(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		param  string
+		files  []string
+		errors []string
+	}{
+		{"errors", "", nil, []string{
+			"errors.proto:6:1: test.errors.NoKey: Missing filed with option: [(Key) = true] or [(Value) = true]",
+			"errors.proto:11:1: test.errors.NoValue: Missing filed with option: [(Key) = true] or [(Value) = true]",
+		}},
+		{"imports", "paths=none", nil, []string{"invalid value for parameter paths:none"}},
+		{"imports", "emit_cpp=false,foo", nil, []string{"unknown parameter:foo"}},
+		{"layout", "paths=flat", []string{"a/data.proto", "b/data.proto"}, []string{
+			"b/data.proto: output data.proto.hpp conflicts with the one of a/data.proto, use paths=source_relative or paths=import",
+		}},
+		{"legacy_errors", "", nil, []string{
+			"legacy_errors.proto:10:1: test.legacy.ListEntry: Invalid option Container:List, expected Hash or Tree",
+			"legacy_errors.proto:14:5: test.legacy.ListEntry.counts: Invalid option Container:Set, expected Hash or Tree",
+		}},
+		{"cycle", "", nil, []string{
+			"cycle.proto:3:1: test.cycle.A: test.cycle.A -> test.cycle.B -> test.cycle.C -> test.cycle.A is a by-value cycle which can not be laid out in shared memory",
+			"cycle.proto:6:1: test.cycle.S: test.cycle.S -> test.cycle.S is a by-value cycle which can not be laid out in shared memory",
+			"cycle.proto:7:1: test.cycle.P: test.cycle.P -> test.cycle.P is a by-value cycle which can not be laid out in shared memory",
+		}},
+		{"containers_errors", "", nil, []string{
+			"containers_errors.proto:13:5: test.containers.FlatEntry.name: Option (mmdata.map_container) is only for map fields",
+			"containers_errors.proto:9:1: test.containers.FlatEntry: Option (mmdata.container) = FLAT_SORTED is not supported for root tables, use FLAT",
+			"containers_errors.proto:16:1: test.containers.Item: Option (mmdata.container) is only for messages with [(Key) = true] and [(Value) = true] fields",
+			"containers_errors.proto:22:1: test.containers.BothEntry: Option Container and (mmdata.container) are both given, keep (mmdata.container) only",
+		}},
+		{"oneofs_errors", "", nil, []string{
+			"oneofs_errors.proto:9:9: test.oneofs.Entry.name: Option [(Key) = true] or [(Value) = true] is not supported on oneof fields",
+			"oneofs_errors.proto:13:9: test.oneofs.Entry.text: Option [(Key) = true] or [(Value) = true] is not supported on oneof fields",
+		}},
+		{"defaults_errors", "", nil, []string{
+			"defaults_errors.proto:8:5: test.defaults.Entry.hex: Invalid option (mmdata.default) \"0x10\":not a valid int32",
+			"defaults_errors.proto:9:5: test.defaults.Entry.flag: Invalid option (mmdata.default) \"1\":not a valid bool",
+			"defaults_errors.proto:10:5: test.defaults.Entry.item: Option (mmdata.default) is not supported for repeated or message fields",
+			"defaults_errors.proto:11:5: test.defaults.Entry.ids: Option (mmdata.default) is not supported for repeated or message fields",
+			"defaults_errors.proto:12:5: test.defaults.Entry.count: Invalid option (mmdata.default) \"-1\":not a valid uint32",
+		}},
+		{"wkt_errors", "", nil, []string{
+			"wkt_errors.proto:8:5: test.wkt.Dynamic.any: Type google.protobuf.Any is not supported, it holds a message of any type",
+			"wkt_errors.proto:9:5: test.wkt.Dynamic.object: Type google.protobuf.Struct is not supported, it holds dynamic json values",
+			"wkt_errors.proto:10:5: test.wkt.Dynamic.values: Type google.protobuf.Value is not supported, it holds dynamic json values",
+			"wkt_errors.proto:11:5: test.wkt.Dynamic.list: Type google.protobuf.ListValue is not supported, it holds dynamic json values",
+			"wkt_errors.proto:12:5: test.wkt.Dynamic.empty: Type google.protobuf.Empty is not supported",
+		}},
+		{"cpptypes_errors", "", nil, []string{
+			"cpptypes_errors.proto:13:3: test.cpptypes.M.h: Invalid option (mmdata.cpp_type):missing name",
+			"cpptypes_errors.proto:6:3: test.cpptypes.M.a: Option (mmdata.cpp_type) float is not valid for int32 fields",
+			"cpptypes_errors.proto:7:3: test.cpptypes.M.b: Option (mmdata.cpp_type) uint8_t is not valid for string fields",
+			"cpptypes_errors.proto:8:3: test.cpptypes.M.c: Option (mmdata.cpp_type) int16_t is not valid for enum fields",
+			"cpptypes_errors.proto:9:3: test.cpptypes.M.d: Option (mmdata.cpp_type) uint8_t is not constructed with an allocator",
+			"cpptypes_errors.proto:10:3: test.cpptypes.M.e: Default 300 overflows (mmdata.cpp_type) uint8_t",
+			"cpptypes_errors.proto:11:3: test.cpptypes.M.f: Invalid option (mmdata.cpp_type):\"my code\" is not a C++ type name",
+			"cpptypes_errors.proto:12:3: test.cpptypes.M.g: Option (mmdata.cpp_type) is not supported for map fields",
+		}},
+		{"fixed_errors", "", nil, []string{
+			"fixed_errors.proto:12:3: test.fixed.M.g: Invalid option (mmdata.fixed_size):0",
+			"fixed_errors.proto:6:3: test.fixed.M.a: Option (mmdata.fixed_size) is not supported for repeated string fields",
+			"fixed_errors.proto:7:3: test.fixed.M.b: Option (mmdata.fixed_size) is not supported for repeated message fields",
+			"fixed_errors.proto:8:3: test.fixed.M.c: Option (mmdata.fixed_size) is only for repeated, string and bytes fields",
+			"fixed_errors.proto:9:3: test.fixed.M.d: Option (mmdata.fixed_size) is not supported for map fields",
+			"fixed_errors.proto:10:3: test.fixed.M.e: Default \"abc\" is over (mmdata.fixed_size) 2",
+			"fixed_errors.proto:11:3: test.fixed.M.f: Option (mmdata.fixed_overflow) is only for fields with (mmdata.fixed_size)",
+			"fixed_errors.proto:13:3: test.fixed.M.h: Option (mmdata.fixed_size) conflicts with (mmdata.cpp_type)",
+			"fixed_errors.proto:14:3: test.fixed.M.i: Option (mmdata.fixed_size) is only for repeated, string and bytes fields",
+		}},
+		{"maps_errors", "", nil, []string{
+			"maps_errors.proto:5:30: test.maps.Shape.N: Option (mmdata.container) is only for top-level messages",
+			"maps_errors.proto:4:17: test.maps.Point.m: Option (mmdata.map_container) = HASH is not supported in key type Point, use TREE or FLAT_SORTED",
+		}},
+		{"keys_errors", "", nil, []string{
+			"keys_errors.proto:6:1: test.keys.Partial: Option (mmdata.key_order) is missing on some fields with [(Key) = true]",
+			"keys_errors.proto:17:5: test.keys.Twice.value: Option (mmdata.key_order) is only for fields with [(Key) = true]",
+			"keys_errors.proto:16:5: test.keys.Twice.id: Duplicate option (mmdata.key_order) = 1",
+		}},
+		{"values_errors", "", nil, []string{
+			"values_errors.proto:8:5: test.values.Row.title: Option [(Value) = true] conflicts with (mmdata.all_values)",
+			"values_errors.proto:9:5: test.values.Row.N: Option (mmdata.all_values) is only for top-level messages",
+			"values_errors.proto:11:1: test.values.NoKey: Missing filed with option: [(Key) = true] or [(Value) = true]",
+		}},
+		{"indexes_errors", "", nil, []string{
+			"indexes_errors.proto:15:5: test.indexes.E.id: Option (mmdata.index) is not supported for key fields",
+			"indexes_errors.proto:8:5: test.indexes.V.b: Duplicate option (mmdata.index) = \"dup\" in table E",
+			"indexes_errors.proto:9:5: test.indexes.V.c: Option (mmdata.index) is not supported for repeated or oneof fields",
+			"indexes_errors.proto:10:5: test.indexes.V.d: Option (mmdata.index) is not supported for message fields",
+			"indexes_errors.proto:11:5: test.indexes.V.e: Invalid option (mmdata.index):\"9e\" is not a C++ identifier",
+		}},
+		{"multi_errors", "", nil, []string{
+			"multi_errors.proto:5:1: test.multi.NoKey: Option (mmdata.table) is only for messages with [(Key) = true] and [(Value) = true] fields",
+			"multi_errors.proto:5:61: test.multi.NoKey.N: Option (mmdata.table) is only for top-level messages",
+			"multi_errors.proto:4:13: test.multi.V.a: Option (mmdata.index) is not supported for tables with (mmdata.table) = MULTI",
+		}},
+		{"duplicates_errors", "", nil, []string{
+			"duplicates_errors.proto:8:1: test.duplicates.NotRepeated: Option (mmdata.duplicate_key) = MERGE is only for a repeated [(Value) = true] field",
+			"duplicates_errors.proto:14:1: test.duplicates.Indexed: Option (mmdata.duplicate_key) = KEEP_LAST is not supported for tables with (mmdata.index)",
+			"duplicates_errors.proto:20:1: test.duplicates.Fixed: Option (mmdata.duplicate_key) = MERGE is not supported for (mmdata.fixed_size) fields",
+			"duplicates_errors.proto:26:1: test.duplicates.Multi: Option (mmdata.duplicate_key) is not supported for tables with (mmdata.table) = MULTI",
+			"duplicates_errors.proto:33:1: test.duplicates.NoTable: Option (mmdata.duplicate_key) is only for messages with [(Key) = true] and [(Value) = true] fields",
+		}},
+		{"flat_errors", "", nil, []string{
+			"flat_errors.proto:9:5: test.flat.NotFlat.m: Option (mmdata.map_container) = FLAT is only for root tables, use FLAT_SORTED",
+			"flat_errors.proto:4:1: test.flat.NotFlat: Option (mmdata.eytzinger) is only for tables with (mmdata.container) = FLAT",
+			"flat_errors.proto:11:1: test.flat.Multi: Option (mmdata.container) = FLAT is not supported for tables with (mmdata.table) = MULTI",
+			"flat_errors.proto:18:1: test.flat.Sorted: Option (mmdata.container) = FLAT_SORTED is not supported for root tables, use FLAT",
+		}},
+		{"perfect_hash_errors", "", nil, []string{
+			"perfect_hash_errors.proto:10:5: test.perfect_hash.Multi.m: Option (mmdata.map_container) = PERFECT_HASH is only for root tables, use FLAT_SORTED",
+			"perfect_hash_errors.proto:4:1: test.perfect_hash.Multi: Option (mmdata.container) = PERFECT_HASH is not supported for tables with (mmdata.table) = MULTI",
+		}},
+	} {
+		response := process(testRequest(t, test.name, test.param, test.files...))
+		if len(response.File) > 0 {
+			t.Errorf("%s.proto with %q generated %d files", test.name, test.param, len(response.File))
+		}
+		if got := strings.Split(response.GetError(), "\n"); !reflect.DeepEqual(got, test.errors) {
+			t.Errorf("%s.proto with %q reported:\n%s\nwant:\n%s", test.name, test.param, strings.Join(got, "\n"), strings.Join(test.errors, "\n"))
+		}
+	}
+}
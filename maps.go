@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// heldMessage returns the message type stored by a field, the value type of
+// a map field, or nil for other types and the well-known ones.
+func (g *Generator) heldMessage(field *descriptor.FieldDescriptorProto) *MessageType {
+	if entry := g.mapEntry(field); nil != entry {
+		field = entry.Field[1]
+	}
+	if field.GetType() != descriptor.FieldDescriptorProto_TYPE_MESSAGE || isWellKnownType(field.GetTypeName()) || len(g.fieldOptions(field).CppType) > 0 {
+		return nil
+	}
+	return g.registry.Message(field.GetTypeName())
+}
+
+// keyHelperTypes returns the message types the key fields need the key
+// helpers of, the key types and the messages held by them, which come first.
+func (g *Generator) keyHelperTypes(fields ...*descriptor.FieldDescriptorProto) []*MessageType {
+	var types []*MessageType
+	visited := make(map[*MessageType]bool)
+	var walk func(t *MessageType)
+	walk = func(t *MessageType) {
+		if visited[t] {
+			return
+		}
+		visited[t] = true
+		for _, f := range t.Desc.Field {
+			if inner := g.heldMessage(f); nil != inner {
+				walk(inner)
+			}
+		}
+		types = append(types, t)
+	}
+	for _, field := range fields {
+		if t := g.heldMessage(field); nil != t {
+			walk(t)
+		}
+	}
+	return types
+}
+
+// verifyMaps reports the options of nested messages which are only for root
+// tables, and the hash map fields of key messages, which have no operator<.
+func (g *Generator) verifyMaps(msg *descriptor.DescriptorProto, kv KeyValueFiled) {
+	var walk func(msg *descriptor.DescriptorProto)
+	walk = func(msg *descriptor.DescriptorProto) {
+		for _, nest := range msg.NestedType {
+			if g.messageOptions(nest).HasContainer {
+				g.messageError(nest, "Option (mmdata.container) is only for top-level messages")
+			}
+			if g.messageOptions(nest).HasTable {
+				g.messageError(nest, "Option (mmdata.table) is only for top-level messages")
+			}
+			if g.messageOptions(nest).AllValues {
+				g.messageError(nest, "Option (mmdata.all_values) is only for top-level messages")
+			}
+			walk(nest)
+		}
+	}
+	walk(msg)
+	for _, t := range g.keyHelperTypes(kv.Keys...) {
+		for _, field := range t.Desc.Field {
+			if g.isMapField(field) && g.fieldOptions(field).Container == ContainerHash {
+				g.fieldError(field, "Option (mmdata.map_container) = HASH is not supported in key type %s, use TREE or FLAT_SORTED", t.Desc.GetName())
+			}
+		}
+	}
+}
+
+// dumpMapHash emits the hash of a map field of a key type. The entries are
+// combined with xor as the order of a hash map is not defined.
+func (g *Generator) dumpMapHash(buf *bytes.Buffer, field *descriptor.FieldDescriptorProto, name string, currentTAB string) {
+	entry := g.mapEntry(field)
+	fmt.Fprintf(buf, "%sfor(%s::const_iterator it = v.%s.begin(); it != v.%s.end(); ++it)\n", currentTAB, g.getFieldType(field), name, name)
+	fmt.Fprintf(buf, "%s{\n", currentTAB)
+	fmt.Fprintf(buf, "%s    std::size_t entry = boost::hash<%s>()(it->first);\n", currentTAB, g.getBaseFieldType(entry.Field[0]))
+	fmt.Fprintf(buf, "%s    boost::hash_combine(entry, boost::hash<%s>()(it->second));\n", currentTAB, g.getBaseFieldType(entry.Field[1]))
+	fmt.Fprintf(buf, "%s    hash ^= entry;\n", currentTAB)
+	fmt.Fprintf(buf, "%s}\n", currentTAB)
+}
+
+// dumpMapPrinter emits the printing of a map field, like "m={k1:v1,k2:v2}".
+func (g *Generator) dumpMapPrinter(buf *bytes.Buffer, field *descriptor.FieldDescriptorProto, name string, sep string, currentTAB string) {
+	fmt.Fprintf(buf, "%sos<<\"%s%s={\";\n", currentTAB, sep, name)
+	fmt.Fprintf(buf, "%sfor(%s::const_iterator it = v.%s.begin(); it != v.%s.end(); ++it)\n", currentTAB, g.getFieldType(field), name, name)
+	fmt.Fprintf(buf, "%s{\n", currentTAB)
+	fmt.Fprintf(buf, "%s    if(it != v.%s.begin()) os<<\",\";\n", currentTAB, name)
+	fmt.Fprintf(buf, "%s    os<<it->first<<\":\"<<it->second;\n", currentTAB)
+	fmt.Fprintf(buf, "%s}\n", currentTAB)
+	fmt.Fprintf(buf, "%sos<<\"}\";\n", currentTAB)
+}
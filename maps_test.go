@@ -0,0 +1,32 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+//go:generate protoc -Itestdata -I. --include_imports --include_source_info --descriptor_set_out=testdata/maps.pb testdata/maps.proto
+//go:generate protoc -Itestdata -I. --include_imports --include_source_info --descriptor_set_out=testdata/maps_errors.pb testdata/maps_errors.proto
+
+func TestMaps(t *testing.T) {
+	header := testGenerate(t, "maps", "")["maps.proto.hpp"]
+	for _, want := range []string{
+		"mmdata::SHMMap<mmdata::SHMString, Point>::Type named;\n            mmdata::SHMFlatMap<int32_t, Color>::Type colors;\n",
+		"mmdata::SHMMap<uint64_t, mmdata::pb::Bytes>::Type data;",
+		"mmdata::SHMHashMap<mmdata::SHMString, Shape>::Type shapes;\n            mmdata::SHMHashMap<bool, double>::Type flags;\n",
+		"os<<\",named={\";\n            for(mmdata::SHMMap<mmdata::SHMString, Point>::Type::const_iterator it = v.named.begin(); it != v.named.end(); ++it)\n            {\n                if(it != v.named.begin()) os<<\",\";\n                os<<it->first<<\":\"<<it->second;\n            }\n",
+		//the messages held by a key message get key helpers too
+		"inline std::size_t hash_value(const Point& v)",
+		"inline bool operator<(const Shape::Inner& a, const Shape::Inner& b)",
+		//map entries are hashed regardless of their order
+		"std::size_t entry = boost::hash<mmdata::SHMString>()(it->first);\n                boost::hash_combine(entry, boost::hash<Point>()(it->second));\n                hash ^= entry;\n",
+	} {
+		if !strings.Contains(header, want) {
+			t.Errorf("maps.proto.hpp does not contain %q:\n%s", want, header)
+		}
+	}
+	//Holder is only a value
+	if strings.Contains(header, "hash_value(const Holder&") {
+		t.Errorf("maps.proto.hpp has key helpers for Holder:\n%s", header)
+	}
+}
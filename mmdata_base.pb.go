@@ -0,0 +1,502 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: mmdata_base.proto
+
+package main
+
+import (
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	descriptor "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+// Container of a root table or a map field.
+type Mmdata_ContainerKind int32
+
+const (
+	Mmdata_HASH         Mmdata_ContainerKind = 0
+	Mmdata_TREE         Mmdata_ContainerKind = 1
+	Mmdata_FLAT_SORTED  Mmdata_ContainerKind = 2
+	Mmdata_FLAT         Mmdata_ContainerKind = 3
+	Mmdata_PERFECT_HASH Mmdata_ContainerKind = 4
+)
+
+var Mmdata_ContainerKind_name = map[int32]string{
+	0: "HASH",
+	1: "TREE",
+	2: "FLAT_SORTED",
+	3: "FLAT",
+	4: "PERFECT_HASH",
+}
+
+var Mmdata_ContainerKind_value = map[string]int32{
+	"HASH":         0,
+	"TREE":         1,
+	"FLAT_SORTED":  2,
+	"FLAT":         3,
+	"PERFECT_HASH": 4,
+}
+
+func (x Mmdata_ContainerKind) Enum() *Mmdata_ContainerKind {
+	p := new(Mmdata_ContainerKind)
+	*p = x
+	return p
+}
+
+func (x Mmdata_ContainerKind) String() string {
+	return proto.EnumName(Mmdata_ContainerKind_name, int32(x))
+}
+
+func (x *Mmdata_ContainerKind) UnmarshalJSON(data []byte) error {
+	value, err := proto.UnmarshalJSONEnum(Mmdata_ContainerKind_value, data, "Mmdata_ContainerKind")
+	if err != nil {
+		return err
+	}
+	*x = Mmdata_ContainerKind(value)
+	return nil
+}
+
+func (Mmdata_ContainerKind) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_3e1548b84ec2a4d0, []int{0, 0}
+}
+
+// Whether a root table holds one entry or several entries per key.
+type Mmdata_TableKind int32
+
+const (
+	Mmdata_UNIQUE Mmdata_TableKind = 0
+	Mmdata_MULTI  Mmdata_TableKind = 1
+)
+
+var Mmdata_TableKind_name = map[int32]string{
+	0: "UNIQUE",
+	1: "MULTI",
+}
+
+var Mmdata_TableKind_value = map[string]int32{
+	"UNIQUE": 0,
+	"MULTI":  1,
+}
+
+func (x Mmdata_TableKind) Enum() *Mmdata_TableKind {
+	p := new(Mmdata_TableKind)
+	*p = x
+	return p
+}
+
+func (x Mmdata_TableKind) String() string {
+	return proto.EnumName(Mmdata_TableKind_name, int32(x))
+}
+
+func (x *Mmdata_TableKind) UnmarshalJSON(data []byte) error {
+	value, err := proto.UnmarshalJSONEnum(Mmdata_TableKind_value, data, "Mmdata_TableKind")
+	if err != nil {
+		return err
+	}
+	*x = Mmdata_TableKind(value)
+	return nil
+}
+
+func (Mmdata_TableKind) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_3e1548b84ec2a4d0, []int{0, 1}
+}
+
+// What Insert() of a root table does with an entry of a key already
+// inserted, the duplicate keys are counted and reported by Build().
+type Mmdata_DuplicateKey int32
+
+const (
+	Mmdata_KEEP_FIRST Mmdata_DuplicateKey = 0
+	Mmdata_KEEP_LAST  Mmdata_DuplicateKey = 1
+	Mmdata_MERGE      Mmdata_DuplicateKey = 2
+	Mmdata_FAIL_BUILD Mmdata_DuplicateKey = 3
+)
+
+var Mmdata_DuplicateKey_name = map[int32]string{
+	0: "KEEP_FIRST",
+	1: "KEEP_LAST",
+	2: "MERGE",
+	3: "FAIL_BUILD",
+}
+
+var Mmdata_DuplicateKey_value = map[string]int32{
+	"KEEP_FIRST": 0,
+	"KEEP_LAST":  1,
+	"MERGE":      2,
+	"FAIL_BUILD": 3,
+}
+
+func (x Mmdata_DuplicateKey) Enum() *Mmdata_DuplicateKey {
+	p := new(Mmdata_DuplicateKey)
+	*p = x
+	return p
+}
+
+func (x Mmdata_DuplicateKey) String() string {
+	return proto.EnumName(Mmdata_DuplicateKey_name, int32(x))
+}
+
+func (x *Mmdata_DuplicateKey) UnmarshalJSON(data []byte) error {
+	value, err := proto.UnmarshalJSONEnum(Mmdata_DuplicateKey_value, data, "Mmdata_DuplicateKey")
+	if err != nil {
+		return err
+	}
+	*x = Mmdata_DuplicateKey(value)
+	return nil
+}
+
+func (Mmdata_DuplicateKey) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_3e1548b84ec2a4d0, []int{0, 2}
+}
+
+// What kcfg parsing does with data over (mmdata.fixed_size).
+type Mmdata_FixedOverflow int32
+
+const (
+	Mmdata_FAIL     Mmdata_FixedOverflow = 0
+	Mmdata_TRUNCATE Mmdata_FixedOverflow = 1
+)
+
+var Mmdata_FixedOverflow_name = map[int32]string{
+	0: "FAIL",
+	1: "TRUNCATE",
+}
+
+var Mmdata_FixedOverflow_value = map[string]int32{
+	"FAIL":     0,
+	"TRUNCATE": 1,
+}
+
+func (x Mmdata_FixedOverflow) Enum() *Mmdata_FixedOverflow {
+	p := new(Mmdata_FixedOverflow)
+	*p = x
+	return p
+}
+
+func (x Mmdata_FixedOverflow) String() string {
+	return proto.EnumName(Mmdata_FixedOverflow_name, int32(x))
+}
+
+func (x *Mmdata_FixedOverflow) UnmarshalJSON(data []byte) error {
+	value, err := proto.UnmarshalJSONEnum(Mmdata_FixedOverflow_value, data, "Mmdata_FixedOverflow")
+	if err != nil {
+		return err
+	}
+	*x = Mmdata_FixedOverflow(value)
+	return nil
+}
+
+func (Mmdata_FixedOverflow) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_3e1548b84ec2a4d0, []int{0, 3}
+}
+
+// Scope of the options, used as (mmdata.container) = TREE.
+type Mmdata struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Mmdata) Reset()         { *m = Mmdata{} }
+func (m *Mmdata) String() string { return proto.CompactTextString(m) }
+func (*Mmdata) ProtoMessage()    {}
+func (*Mmdata) Descriptor() ([]byte, []int) {
+	return fileDescriptor_3e1548b84ec2a4d0, []int{0}
+}
+
+func (m *Mmdata) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Mmdata.Unmarshal(m, b)
+}
+func (m *Mmdata) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Mmdata.Marshal(b, m, deterministic)
+}
+func (m *Mmdata) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Mmdata.Merge(m, src)
+}
+func (m *Mmdata) XXX_Size() int {
+	return xxx_messageInfo_Mmdata.Size(m)
+}
+func (m *Mmdata) XXX_DiscardUnknown() {
+	xxx_messageInfo_Mmdata.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Mmdata proto.InternalMessageInfo
+
+var E_Mmdata_Container = &proto.ExtensionDesc{
+	ExtendedType:  (*descriptor.MessageOptions)(nil),
+	ExtensionType: (*Mmdata_ContainerKind)(nil),
+	Field:         51248,
+	Name:          "mmdata.container",
+	Tag:           "varint,51248,opt,name=container,enum=Mmdata_ContainerKind",
+	Filename:      "mmdata_base.proto",
+}
+
+var E_Mmdata_AllValues = &proto.ExtensionDesc{
+	ExtendedType:  (*descriptor.MessageOptions)(nil),
+	ExtensionType: (*bool)(nil),
+	Field:         51242,
+	Name:          "mmdata.all_values",
+	Tag:           "varint,51242,opt,name=all_values",
+	Filename:      "mmdata_base.proto",
+}
+
+var E_Mmdata_Table = &proto.ExtensionDesc{
+	ExtendedType:  (*descriptor.MessageOptions)(nil),
+	ExtensionType: (*Mmdata_TableKind)(nil),
+	Field:         51245,
+	Name:          "mmdata.table",
+	Tag:           "varint,51245,opt,name=table,enum=Mmdata_TableKind",
+	Filename:      "mmdata_base.proto",
+}
+
+var E_Mmdata_DuplicateKey = &proto.ExtensionDesc{
+	ExtendedType:  (*descriptor.MessageOptions)(nil),
+	ExtensionType: (*Mmdata_DuplicateKey)(nil),
+	Field:         51246,
+	Name:          "mmdata.duplicate_key",
+	Tag:           "varint,51246,opt,name=duplicate_key,enum=Mmdata_DuplicateKey",
+	Filename:      "mmdata_base.proto",
+}
+
+var E_Mmdata_Eytzinger = &proto.ExtensionDesc{
+	ExtendedType:  (*descriptor.MessageOptions)(nil),
+	ExtensionType: (*bool)(nil),
+	Field:         51247,
+	Name:          "mmdata.eytzinger",
+	Tag:           "varint,51247,opt,name=eytzinger",
+	Filename:      "mmdata_base.proto",
+}
+
+var E_Mmdata_MapContainer = &proto.ExtensionDesc{
+	ExtendedType:  (*descriptor.FieldOptions)(nil),
+	ExtensionType: (*Mmdata_ContainerKind)(nil),
+	Field:         51249,
+	Name:          "mmdata.map_container",
+	Tag:           "varint,51249,opt,name=map_container,enum=Mmdata_ContainerKind",
+	Filename:      "mmdata_base.proto",
+}
+
+var E_Mmdata_Default = &proto.ExtensionDesc{
+	ExtendedType:  (*descriptor.FieldOptions)(nil),
+	ExtensionType: (*string)(nil),
+	Field:         51237,
+	Name:          "mmdata.default",
+	Tag:           "bytes,51237,opt,name=default",
+	Filename:      "mmdata_base.proto",
+}
+
+var E_Mmdata_CppType = &proto.ExtensionDesc{
+	ExtendedType:  (*descriptor.FieldOptions)(nil),
+	ExtensionType: (*Mmdata_CppType)(nil),
+	Field:         51238,
+	Name:          "mmdata.cpp_type",
+	Tag:           "bytes,51238,opt,name=cpp_type",
+	Filename:      "mmdata_base.proto",
+}
+
+var E_Mmdata_FixedSize = &proto.ExtensionDesc{
+	ExtendedType:  (*descriptor.FieldOptions)(nil),
+	ExtensionType: (*uint32)(nil),
+	Field:         51239,
+	Name:          "mmdata.fixed_size",
+	Tag:           "varint,51239,opt,name=fixed_size",
+	Filename:      "mmdata_base.proto",
+}
+
+var E_Mmdata_FixedOverflow = &proto.ExtensionDesc{
+	ExtendedType:  (*descriptor.FieldOptions)(nil),
+	ExtensionType: (*Mmdata_FixedOverflow)(nil),
+	Field:         51240,
+	Name:          "mmdata.fixed_overflow",
+	Tag:           "varint,51240,opt,name=fixed_overflow,enum=Mmdata_FixedOverflow",
+	Filename:      "mmdata_base.proto",
+}
+
+var E_Mmdata_KeyOrder = &proto.ExtensionDesc{
+	ExtendedType:  (*descriptor.FieldOptions)(nil),
+	ExtensionType: (*uint32)(nil),
+	Field:         51241,
+	Name:          "mmdata.key_order",
+	Tag:           "varint,51241,opt,name=key_order",
+	Filename:      "mmdata_base.proto",
+}
+
+var E_Mmdata_Index = &proto.ExtensionDesc{
+	ExtendedType:  (*descriptor.FieldOptions)(nil),
+	ExtensionType: (*string)(nil),
+	Field:         51243,
+	Name:          "mmdata.index",
+	Tag:           "bytes,51243,opt,name=index",
+	Filename:      "mmdata_base.proto",
+}
+
+var E_Mmdata_UniqueIndex = &proto.ExtensionDesc{
+	ExtendedType:  (*descriptor.FieldOptions)(nil),
+	ExtensionType: (*bool)(nil),
+	Field:         51244,
+	Name:          "mmdata.unique_index",
+	Tag:           "varint,51244,opt,name=unique_index",
+	Filename:      "mmdata_base.proto",
+}
+
+// C++ type of a field, used as [(mmdata.cpp_type) = {name: "uint16_t"}].
+type Mmdata_CppType struct {
+	// A fixed-width integer, float, double or bool type valid for the
+	// field type, or any type providing the operators of the field.
+	Name *string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	// Whether the type is constructed with the mmdata::CharAllocator.
+	Allocator *bool `protobuf:"varint,2,opt,name=allocator" json:"allocator,omitempty"`
+	// Header declaring the type, included by the generated header.
+	Include              *string  `protobuf:"bytes,3,opt,name=include" json:"include,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Mmdata_CppType) Reset()         { *m = Mmdata_CppType{} }
+func (m *Mmdata_CppType) String() string { return proto.CompactTextString(m) }
+func (*Mmdata_CppType) ProtoMessage()    {}
+func (*Mmdata_CppType) Descriptor() ([]byte, []int) {
+	return fileDescriptor_3e1548b84ec2a4d0, []int{0, 0}
+}
+
+func (m *Mmdata_CppType) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Mmdata_CppType.Unmarshal(m, b)
+}
+func (m *Mmdata_CppType) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Mmdata_CppType.Marshal(b, m, deterministic)
+}
+func (m *Mmdata_CppType) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Mmdata_CppType.Merge(m, src)
+}
+func (m *Mmdata_CppType) XXX_Size() int {
+	return xxx_messageInfo_Mmdata_CppType.Size(m)
+}
+func (m *Mmdata_CppType) XXX_DiscardUnknown() {
+	xxx_messageInfo_Mmdata_CppType.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Mmdata_CppType proto.InternalMessageInfo
+
+func (m *Mmdata_CppType) GetName() string {
+	if m != nil && m.Name != nil {
+		return *m.Name
+	}
+	return ""
+}
+
+func (m *Mmdata_CppType) GetAllocator() bool {
+	if m != nil && m.Allocator != nil {
+		return *m.Allocator
+	}
+	return false
+}
+
+func (m *Mmdata_CppType) GetInclude() string {
+	if m != nil && m.Include != nil {
+		return *m.Include
+	}
+	return ""
+}
+
+var E_Key = &proto.ExtensionDesc{
+	ExtendedType:  (*descriptor.FieldOptions)(nil),
+	ExtensionType: (*bool)(nil),
+	Field:         51234,
+	Name:          "Key",
+	Tag:           "varint,51234,opt,name=Key",
+	Filename:      "mmdata_base.proto",
+}
+
+var E_Value = &proto.ExtensionDesc{
+	ExtendedType:  (*descriptor.FieldOptions)(nil),
+	ExtensionType: (*bool)(nil),
+	Field:         51235,
+	Name:          "Value",
+	Tag:           "varint,51235,opt,name=Value",
+	Filename:      "mmdata_base.proto",
+}
+
+func init() {
+	proto.RegisterEnum("Mmdata_ContainerKind", Mmdata_ContainerKind_name, Mmdata_ContainerKind_value)
+	proto.RegisterEnum("Mmdata_TableKind", Mmdata_TableKind_name, Mmdata_TableKind_value)
+	proto.RegisterEnum("Mmdata_DuplicateKey", Mmdata_DuplicateKey_name, Mmdata_DuplicateKey_value)
+	proto.RegisterEnum("Mmdata_FixedOverflow", Mmdata_FixedOverflow_name, Mmdata_FixedOverflow_value)
+	proto.RegisterExtension(E_Mmdata_Container)
+	proto.RegisterExtension(E_Mmdata_AllValues)
+	proto.RegisterExtension(E_Mmdata_Table)
+	proto.RegisterExtension(E_Mmdata_DuplicateKey)
+	proto.RegisterExtension(E_Mmdata_Eytzinger)
+	proto.RegisterExtension(E_Mmdata_MapContainer)
+	proto.RegisterExtension(E_Mmdata_Default)
+	proto.RegisterExtension(E_Mmdata_CppType)
+	proto.RegisterExtension(E_Mmdata_FixedSize)
+	proto.RegisterExtension(E_Mmdata_FixedOverflow)
+	proto.RegisterExtension(E_Mmdata_KeyOrder)
+	proto.RegisterExtension(E_Mmdata_Index)
+	proto.RegisterExtension(E_Mmdata_UniqueIndex)
+	proto.RegisterType((*Mmdata)(nil), "mmdata")
+	proto.RegisterType((*Mmdata_CppType)(nil), "mmdata.CppType")
+	proto.RegisterExtension(E_Key)
+	proto.RegisterExtension(E_Value)
+}
+
+func init() {
+	proto.RegisterFile("mmdata_base.proto", fileDescriptor_3e1548b84ec2a4d0)
+}
+
+var fileDescriptor_3e1548b84ec2a4d0 = []byte{
+	// 656 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x94, 0xcd, 0x4e, 0xdb, 0x40,
+	0x10, 0xc7, 0x13, 0x42, 0x48, 0x3c, 0xc4, 0xb0, 0x58, 0xad, 0x14, 0xa1, 0x56, 0x45, 0xb9, 0x94,
+	0x53, 0x50, 0x57, 0xe2, 0x50, 0x54, 0xb5, 0x0d, 0xc1, 0x29, 0x6e, 0x02, 0xa1, 0x1b, 0x87, 0x4b,
+	0x5b, 0x59, 0x8b, 0xbd, 0x89, 0x56, 0x38, 0xb6, 0xeb, 0x0f, 0x4a, 0x78, 0x84, 0x9e, 0x78, 0x86,
+	0x56, 0xfd, 0xfe, 0x7e, 0xc3, 0xca, 0x6b, 0x3b, 0x40, 0x2b, 0xd5, 0xdc, 0x76, 0xd6, 0xf3, 0xfb,
+	0x6b, 0xf6, 0x3f, 0x33, 0x86, 0x95, 0xc9, 0xc4, 0xa2, 0x21, 0x35, 0x8e, 0x68, 0xc0, 0x9a, 0x9e,
+	0xef, 0x86, 0xee, 0xea, 0xda, 0xd8, 0x75, 0xc7, 0x36, 0xdb, 0x10, 0xd1, 0x51, 0x34, 0xda, 0xb0,
+	0x58, 0x60, 0xfa, 0xdc, 0x0b, 0x5d, 0x3f, 0xc9, 0x68, 0xbc, 0x01, 0x58, 0x48, 0xb8, 0xd5, 0x21,
+	0x54, 0xda, 0x9e, 0xa7, 0x4f, 0x3d, 0xa6, 0x28, 0x30, 0xef, 0xd0, 0x09, 0xab, 0x17, 0xd7, 0x8a,
+	0xeb, 0x12, 0x11, 0x67, 0xe5, 0x16, 0x48, 0xd4, 0xb6, 0x5d, 0x93, 0x86, 0xae, 0x5f, 0x9f, 0x5b,
+	0x2b, 0xae, 0x57, 0xc9, 0xc5, 0x85, 0x52, 0x87, 0x0a, 0x77, 0x4c, 0x3b, 0xb2, 0x58, 0xbd, 0x24,
+	0xa0, 0x2c, 0x6c, 0x1c, 0x80, 0xdc, 0x76, 0x9d, 0x90, 0x72, 0x87, 0xf9, 0x5d, 0xee, 0x58, 0x4a,
+	0x15, 0xe6, 0x77, 0x5b, 0x83, 0x5d, 0x54, 0x88, 0x4f, 0x3a, 0x51, 0x55, 0x54, 0x54, 0x96, 0x61,
+	0xb1, 0xd3, 0x6b, 0xe9, 0xc6, 0xa0, 0x4f, 0x74, 0x75, 0x07, 0xcd, 0xc5, 0x9f, 0xe2, 0x0b, 0x54,
+	0x52, 0x10, 0xd4, 0x0e, 0x54, 0xd2, 0x51, 0xdb, 0xba, 0x21, 0xb0, 0xf9, 0x46, 0x03, 0x24, 0x9d,
+	0x1e, 0xd9, 0x4c, 0xa8, 0x01, 0x2c, 0x0c, 0xf7, 0xb5, 0x67, 0x43, 0x15, 0x15, 0x14, 0x09, 0xca,
+	0x7b, 0xc3, 0x9e, 0xae, 0xa1, 0x62, 0x63, 0x17, 0x6a, 0x3b, 0x91, 0x67, 0x73, 0x93, 0x86, 0xac,
+	0xcb, 0xa6, 0xca, 0x12, 0x40, 0x57, 0x55, 0x0f, 0x8c, 0x8e, 0x46, 0x06, 0x3a, 0x2a, 0x28, 0x32,
+	0x48, 0x22, 0xee, 0xb5, 0x06, 0x3a, 0x2a, 0x0a, 0x52, 0x25, 0x4f, 0x54, 0x34, 0x17, 0x67, 0x76,
+	0x5a, 0x5a, 0xcf, 0xd8, 0x1e, 0x6a, 0xbd, 0x1d, 0x54, 0x6a, 0xdc, 0x05, 0xb9, 0xc3, 0x4f, 0x99,
+	0xd5, 0x3f, 0x61, 0xfe, 0xc8, 0x76, 0x5f, 0x8b, 0xd2, 0x5a, 0x5a, 0x0f, 0x15, 0x94, 0x1a, 0x54,
+	0x75, 0x32, 0xdc, 0x6f, 0xb7, 0x74, 0x15, 0x15, 0xf1, 0x21, 0x48, 0x66, 0xf6, 0x50, 0xe5, 0x4e,
+	0x33, 0xb1, 0xbe, 0x99, 0x59, 0xdf, 0xdc, 0x63, 0x41, 0x40, 0xc7, 0xac, 0xef, 0x85, 0xdc, 0x75,
+	0x82, 0xfa, 0xaf, 0xf3, 0xd8, 0xa7, 0x25, 0x7c, 0xb3, 0x99, 0xd8, 0xdf, 0xbc, 0x62, 0x12, 0xb9,
+	0x90, 0xc2, 0x8f, 0x01, 0xa8, 0x6d, 0x1b, 0x27, 0xd4, 0x8e, 0x58, 0x90, 0x2f, 0xfc, 0xe5, 0xbc,
+	0x34, 0x6b, 0xce, 0xa1, 0x60, 0xf0, 0x53, 0x28, 0x87, 0xb1, 0x61, 0xf9, 0xf0, 0xf7, 0xb4, 0xaa,
+	0x95, 0xac, 0xaa, 0x99, 0xd1, 0x24, 0x91, 0xc0, 0x2f, 0x40, 0xb6, 0x32, 0x63, 0x8d, 0x63, 0x36,
+	0xcd, 0xd7, 0xfc, 0x91, 0x6a, 0xde, 0xc8, 0x34, 0x2f, 0x37, 0x86, 0xd4, 0xac, 0x4b, 0x11, 0x7e,
+	0x04, 0x12, 0x9b, 0x86, 0x67, 0xdc, 0x19, 0x5f, 0xc7, 0xc3, 0x9f, 0xd9, 0x53, 0x67, 0x0c, 0x7e,
+	0x0e, 0xf2, 0x84, 0x7a, 0xc6, 0x45, 0x23, 0x6e, 0xff, 0x23, 0xd2, 0xe1, 0xcc, 0xb6, 0x32, 0x89,
+	0xdf, 0xff, 0x6f, 0x43, 0x6d, 0x42, 0xbd, 0xd9, 0x0d, 0xbe, 0x0f, 0x15, 0x8b, 0x8d, 0x68, 0x64,
+	0x87, 0x79, 0xb2, 0xef, 0xcf, 0xd3, 0x2d, 0x48, 0xf3, 0x71, 0x17, 0xaa, 0xa6, 0xe7, 0x19, 0x61,
+	0xbc, 0x5d, 0x39, 0xec, 0x07, 0xc1, 0x2e, 0xe2, 0xe5, 0x59, 0x49, 0xc9, 0x56, 0x92, 0x8a, 0x99,
+	0x1c, 0xf0, 0x43, 0x80, 0x51, 0x3c, 0x92, 0x46, 0xc0, 0xcf, 0x72, 0xe5, 0x3e, 0x0a, 0x39, 0x99,
+	0x48, 0x02, 0x19, 0xf0, 0x33, 0x86, 0x5f, 0xc2, 0x52, 0xc2, 0xbb, 0xd9, 0x4c, 0xe7, 0x68, 0x7c,
+	0xfa, 0xdb, 0xa5, 0x2b, 0x1b, 0x41, 0xe4, 0xd1, 0xe5, 0x10, 0x3f, 0x00, 0xe9, 0x98, 0x4d, 0x0d,
+	0xd7, 0xb7, 0xf2, 0xfd, 0xff, 0x9c, 0x56, 0x57, 0x3d, 0x66, 0xd3, 0x7e, 0x0c, 0xe0, 0x4d, 0x28,
+	0x73, 0xc7, 0x62, 0xa7, 0x79, 0xe4, 0xd7, 0xd4, 0xe2, 0x24, 0x1b, 0x6f, 0x43, 0x2d, 0x72, 0xf8,
+	0xab, 0x88, 0x19, 0xd7, 0xa2, 0xbf, 0xa5, 0xa3, 0xb3, 0x98, 0x40, 0x5a, 0xcc, 0x6c, 0xdd, 0x83,
+	0x52, 0xfc, 0xaf, 0xc8, 0x41, 0xdf, 0xa6, 0x68, 0x9c, 0xbb, 0xb5, 0x09, 0x65, 0xb1, 0x64, 0x79,
+	0xd0, 0xbb, 0x14, 0x4a, 0xb2, 0xff, 0x0c, 0x00, 0xa3, 0xb7, 0x4c, 0x34, 0xac, 0x05, 0x00, 0x00,
+}
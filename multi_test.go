@@ -0,0 +1,31 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+//go:generate protoc -Itestdata -I. --include_imports --include_source_info --descriptor_set_out=testdata/multi.pb testdata/multi.proto
+//go:generate protoc -Itestdata -I. --include_imports --include_source_info --descriptor_set_out=testdata/multi_errors.pb testdata/multi_errors.proto
+
+func TestMultiTables(t *testing.T) {
+	files := testGenerate(t, "multi", "")
+	header := files["multi.proto.hpp"]
+	for _, want := range []string{
+		"typedef mmdata::SHMHashMultiMap<int64_t, mmdata::SHMString>::Type ClickTableParent;",
+		"typedef mmdata::SHMMultiMap<Ordered::key_type, int32_t>::Type OrderedTableParent;",
+		"bool Insert(const Click& entry) { insert(value_type(entry.GetKey(), entry.GetValue())); return true;} ",
+		"std::pair<const_iterator, const_iterator> EqualRange(const key_type& key) const { return equal_range(key);} ",
+	} {
+		if !strings.Contains(header, want) {
+			t.Errorf("multi.proto.hpp does not contain %q:\n%s", want, header)
+		}
+	}
+	if source := files["multi.proto.cpp"]; !strings.Contains(source, "std::pair<RootTable::const_iterator, RootTable::const_iterator> range = root->EqualRange(key);") {
+		t.Errorf("multi.proto.cpp does not print the entries of a key:\n%s", source)
+	}
+}
+
+func TestMultiTablesBuild(t *testing.T) {
+	testCppChecks(t, "multi")
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+//go:generate protoc -Itestdata -I. --include_imports --include_source_info --descriptor_set_out=testdata/nested.pb testdata/nested.proto
+
+func TestNestedMessages(t *testing.T) {
+	tests := []struct {
+		param string
+		want  []string
+	}{
+		{"", []string{
+			"        struct Outer\n        {\n            struct Inner\n            {\n                struct Deep\n",
+			"                Outer::Inner::Deep d;\n",
+			"            mmdata::SHMVector<Outer::Inner::Deep>::Type deeps;\n",
+			"inline std::ostream& operator<<(std::ostream& os, const Outer::Inner::Deep& v)",
+			"typedef mmdata::SHMHashMap<Data::K, Outer::Inner>::Type DataTableParent;",
+		}},
+		{"nested=mangle", []string{
+			"        struct Outer_Inner_Deep\n",
+			"            typedef Outer_Inner_Deep Deep;\n            Outer_Inner_Deep d;\n",
+			"            typedef Outer_Inner Inner;\n            Outer_Inner in;\n            mmdata::SHMVector<Outer_Inner_Deep>::Type deeps;\n",
+			"typedef mmdata::SHMHashMap<Data_K, Outer_Inner>::Type DataTableParent;",
+		}},
+	}
+	for _, test := range tests {
+		header := testGenerate(t, "nested", test.param)["nested.proto.hpp"]
+		for _, want := range test.want {
+			if !strings.Contains(header, want) {
+				t.Errorf("nested.proto.hpp with %q does not contain %q:\n%s", test.param, want, header)
+			}
+		}
+	}
+	//mangled names are declared before their use
+	header := testGenerate(t, "nested", "nested=mangle")["nested.proto.hpp"]
+	if strings.Index(header, "struct Outer_Inner_Deep\n") > strings.Index(header, "struct Outer_Inner\n") ||
+		strings.Index(header, "struct Outer_Inner\n") > strings.Index(header, "struct Outer\n") {
+		t.Errorf("nested.proto.hpp with nested=mangle declares a struct after its use:\n%s", header)
+	}
+}
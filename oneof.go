@@ -0,0 +1,398 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// member is a data member of the struct of a message, a field or the tagged
+// union of the fields of a oneof.
+type member struct {
+	name  string
+	field *descriptor.FieldDescriptorProto
+	//index in OneofDecl for a oneof, -1 for a field
+	oneof int
+}
+
+// structMembers returns the data members of a message in field order, a
+// oneof takes the place of its first field.
+func structMembers(msg *descriptor.DescriptorProto) []member {
+	var members []member
+	seen := make(map[int32]bool)
+	for _, field := range msg.Field {
+		if !isOneofField(field) {
+			members = append(members, member{name: field.GetName(), field: field, oneof: -1})
+			continue
+		}
+		idx := field.GetOneofIndex()
+		if seen[idx] {
+			continue
+		}
+		seen[idx] = true
+		members = append(members, member{name: msg.OneofDecl[idx].GetName(), oneof: int(idx)})
+	}
+	return members
+}
+
+// isOneofField returns whether a field is in a oneof, the synthetic oneof of
+// a proto3 optional field excluded.
+func isOneofField(field *descriptor.FieldDescriptorProto) bool {
+	return nil != field.OneofIndex && !field.GetProto3Optional()
+}
+
+// oneofIndexes returns the indexes of the oneofs of a message in OneofDecl,
+// without the synthetic ones of proto3 optional fields.
+func oneofIndexes(msg *descriptor.DescriptorProto) []int {
+	var indexes []int
+	for i := range msg.OneofDecl {
+		if len(oneofFields(msg, i)) > 0 {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// oneofFields returns the fields of a oneof in declaration order.
+func oneofFields(msg *descriptor.DescriptorProto, index int) []*descriptor.FieldDescriptorProto {
+	var fields []*descriptor.FieldDescriptorProto
+	for _, field := range msg.Field {
+		if isOneofField(field) && int(field.GetOneofIndex()) == index {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// camelCase converts a proto name like "my_choice" into "MyChoice".
+func camelCase(name string) string {
+	var ss []string
+	for _, s := range strings.Split(name, "_") {
+		if len(s) > 0 {
+			ss = append(ss, strings.ToUpper(s[:1])+s[1:])
+		}
+	}
+	return strings.Join(ss, "")
+}
+
+// oneofLocalName is the name of the union typedef-ed in the struct, like
+// "ChoiceOneof" for the oneof "choice".
+func oneofLocalName(oneof *descriptor.OneofDescriptorProto) string {
+	return camelCase(oneof.GetName()) + "Oneof"
+}
+
+// oneofTypeName returns the name of the union template in the namespace of
+// the file, like "Outer_Inner_ChoiceOneof".
+func (g *Generator) oneofTypeName(msg *descriptor.DescriptorProto, index int) string {
+	prefix := msg.GetName()
+	if t := g.registry.MessageOf(msg); nil != t {
+		prefix = strings.Replace(t.LocalName(), ".", "_", -1)
+	}
+	return prefix + "_" + oneofLocalName(msg.OneofDecl[index])
+}
+
+func oneofCase(field *descriptor.FieldDescriptorProto) string {
+	return "k" + camelCase(field.GetName())
+}
+
+func oneofNotSet(oneof *descriptor.OneofDescriptorProto) string {
+	return strings.ToUpper(oneof.GetName()) + "_NOT_SET"
+}
+
+// oneofParams returns the template parameters of a union, one type per field.
+func oneofParams(fields []*descriptor.FieldDescriptorProto) (string, string) {
+	var params, args []string
+	for _, field := range fields {
+		params = append(params, "typename "+field.GetName()+"_type")
+		args = append(args, field.GetName()+"_type")
+	}
+	return strings.Join(params, ", "), strings.Join(args, ", ")
+}
+
+// oneofType returns the instance of the union template used by the struct.
+func (g *Generator) oneofType(msg *descriptor.DescriptorProto, index int) string {
+	var types []string
+	for _, field := range oneofFields(msg, index) {
+		types = append(types, g.getBaseFieldType(field))
+	}
+	return fmt.Sprintf("%s<%s>", g.oneofTypeName(msg, index), strings.Join(types, ", "))
+}
+
+// DumpOneofs emits the tagged unions of all oneofs declared in the file and
+// their kcfg json mapping. The unions are templates over the field types, so
+// that they only need the field types where a struct uses them.
+func (g *Generator) DumpOneofs(file *descriptor.FileDescriptorProto) {
+	var msgs []*descriptor.DescriptorProto
+	for _, msg := range file.MessageType {
+		for _, m := range flattenMessages(msg) {
+			if len(oneofIndexes(m)) > 0 {
+				msgs = append(msgs, m)
+			}
+		}
+	}
+	if len(msgs) == 0 {
+		return
+	}
+	buf := &g.OutputBuffer
+	tab, tabs := g.dumpNamespaceOpen(buf, file.GetPackage())
+	for _, msg := range msgs {
+		for _, i := range oneofIndexes(msg) {
+			g.dumpOneof(buf, msg, i, tab)
+		}
+	}
+	g.dumpNamespaceClose(buf, tabs)
+
+	fmt.Fprintf(buf, "namespace kcfg\n{\n")
+	for _, msg := range msgs {
+		for _, i := range oneofIndexes(msg) {
+			g.dumpOneofKcfg(buf, msg, i, "    ")
+		}
+	}
+	fmt.Fprintf(buf, "}\n\n")
+}
+
+// dumpOneof emits a union with a discriminator. The fields are constructed in
+// place with the allocator of the union, and destroyed when another one is set.
+func (g *Generator) dumpOneof(buf *bytes.Buffer, msg *descriptor.DescriptorProto, index int, currentTAB string) {
+	oneof := msg.OneofDecl[index]
+	fields := oneofFields(msg, index)
+	name := g.oneofTypeName(msg, index)
+	notSet := oneofNotSet(oneof)
+	params, args := oneofParams(fields)
+	funcTab := currentTAB + "    "
+	bodyTab := funcTab + "    "
+	caseTab := bodyTab + "    "
+
+	fmt.Fprintf(buf, "%stemplate<%s>\n", currentTAB, params)
+	fmt.Fprintf(buf, "%sstruct %s\n", currentTAB, name)
+	fmt.Fprintf(buf, "%s{\n", currentTAB)
+	fmt.Fprintf(buf, "%senum Case : int32_t\n", funcTab)
+	fmt.Fprintf(buf, "%s{\n", funcTab)
+	fmt.Fprintf(buf, "%s%s = 0,\n", bodyTab, notSet)
+	for _, field := range fields {
+		fmt.Fprintf(buf, "%s%s = %d,\n", bodyTab, oneofCase(field), field.GetNumber())
+	}
+	fmt.Fprintf(buf, "%s};\n\n", funcTab)
+
+	//constructors, assignment and destructor
+	fmt.Fprintf(buf, "%s%s(const mmdata::CharAllocator& alloc):alloc_(alloc),case_(%s)\n", funcTab, name, notSet)
+	fmt.Fprintf(buf, "%s{}\n", funcTab)
+	fmt.Fprintf(buf, "%s%s(const %s& other):alloc_(other.alloc_),case_(%s)\n", funcTab, name, name, notSet)
+	fmt.Fprintf(buf, "%s{\n", funcTab)
+	fmt.Fprintf(buf, "%sCopyFrom(other);\n", bodyTab)
+	fmt.Fprintf(buf, "%s}\n", funcTab)
+	fmt.Fprintf(buf, "%s%s& operator=(const %s& other)\n", funcTab, name, name)
+	fmt.Fprintf(buf, "%s{\n", funcTab)
+	fmt.Fprintf(buf, "%sif(this != &other)\n", bodyTab)
+	fmt.Fprintf(buf, "%s{\n", bodyTab)
+	fmt.Fprintf(buf, "%sclear();\n", caseTab)
+	fmt.Fprintf(buf, "%sCopyFrom(other);\n", caseTab)
+	fmt.Fprintf(buf, "%s}\n", bodyTab)
+	fmt.Fprintf(buf, "%sreturn *this;\n", bodyTab)
+	fmt.Fprintf(buf, "%s}\n", funcTab)
+	fmt.Fprintf(buf, "%s~%s()\n", funcTab, name)
+	fmt.Fprintf(buf, "%s{\n", funcTab)
+	fmt.Fprintf(buf, "%sclear();\n", bodyTab)
+	fmt.Fprintf(buf, "%s}\n\n", funcTab)
+
+	fmt.Fprintf(buf, "%sCase which() const { return case_; }\n", funcTab)
+	fmt.Fprintf(buf, "%svoid clear()\n", funcTab)
+	fmt.Fprintf(buf, "%s{\n", funcTab)
+	fmt.Fprintf(buf, "%sswitch(case_)\n", bodyTab)
+	fmt.Fprintf(buf, "%s{\n", bodyTab)
+	for _, field := range fields {
+		fmt.Fprintf(buf, "%scase %s: u_.%s.~%s_type(); break;\n", caseTab, oneofCase(field), field.GetName(), field.GetName())
+	}
+	fmt.Fprintf(buf, "%sdefault: break;\n", caseTab)
+	fmt.Fprintf(buf, "%s}\n", bodyTab)
+	fmt.Fprintf(buf, "%scase_ = %s;\n", bodyTab, notSet)
+	fmt.Fprintf(buf, "%s}\n\n", funcTab)
+
+	//typed accessors, mutable_xxx() switches the union to the field
+	for _, field := range fields {
+		fname := field.GetName()
+		ftype := fname + "_type"
+		fmt.Fprintf(buf, "%sbool has_%s() const { return case_ == %s; }\n", funcTab, fname, oneofCase(field))
+		fmt.Fprintf(buf, "%sconst %s& %s() const { return u_.%s; }\n", funcTab, ftype, fname, fname)
+		fmt.Fprintf(buf, "%s%s& mutable_%s()\n", funcTab, ftype, fname)
+		fmt.Fprintf(buf, "%s{\n", funcTab)
+		fmt.Fprintf(buf, "%sif(case_ != %s)\n", bodyTab, oneofCase(field))
+		fmt.Fprintf(buf, "%s{\n", bodyTab)
+		fmt.Fprintf(buf, "%sclear();\n", caseTab)
+		if g.isComplextType(field, false) {
+			fmt.Fprintf(buf, "%snew (&u_.%s) %s(alloc_);\n", caseTab, fname, ftype)
+		} else {
+			fmt.Fprintf(buf, "%snew (&u_.%s) %s();\n", caseTab, fname, ftype)
+		}
+		fmt.Fprintf(buf, "%scase_ = %s;\n", caseTab, oneofCase(field))
+		fmt.Fprintf(buf, "%s}\n", bodyTab)
+		fmt.Fprintf(buf, "%sreturn u_.%s;\n", bodyTab, fname)
+		fmt.Fprintf(buf, "%s}\n", funcTab)
+		fmt.Fprintf(buf, "%svoid set_%s(const %s& v) { mutable_%s() = v; }\n\n", funcTab, fname, ftype, fname)
+	}
+
+	//visitors are called with the set field, and return false if none is set
+	for _, constness := range []string{" const", ""} {
+		fmt.Fprintf(buf, "%stemplate<typename Visitor>\n", funcTab)
+		fmt.Fprintf(buf, "%sbool visit(Visitor& visitor)%s\n", funcTab, constness)
+		fmt.Fprintf(buf, "%s{\n", funcTab)
+		fmt.Fprintf(buf, "%sswitch(case_)\n", bodyTab)
+		fmt.Fprintf(buf, "%s{\n", bodyTab)
+		for _, field := range fields {
+			fmt.Fprintf(buf, "%scase %s: visitor(u_.%s); return true;\n", caseTab, oneofCase(field), field.GetName())
+		}
+		fmt.Fprintf(buf, "%sdefault: return false;\n", caseTab)
+		fmt.Fprintf(buf, "%s}\n", bodyTab)
+		fmt.Fprintf(buf, "%s}\n", funcTab)
+	}
+
+	fmt.Fprintf(buf, "\n%sbool operator==(const %s& other) const\n", funcTab, name)
+	fmt.Fprintf(buf, "%s{\n", funcTab)
+	fmt.Fprintf(buf, "%sif(case_ != other.case_) return false;\n", bodyTab)
+	fmt.Fprintf(buf, "%sswitch(case_)\n", bodyTab)
+	fmt.Fprintf(buf, "%s{\n", bodyTab)
+	for _, field := range fields {
+		fmt.Fprintf(buf, "%scase %s: return u_.%s == other.u_.%s;\n", caseTab, oneofCase(field), field.GetName(), field.GetName())
+	}
+	fmt.Fprintf(buf, "%sdefault: return true;\n", caseTab)
+	fmt.Fprintf(buf, "%s}\n", bodyTab)
+	fmt.Fprintf(buf, "%s}\n", funcTab)
+	fmt.Fprintf(buf, "%sbool operator<(const %s& other) const\n", funcTab, name)
+	fmt.Fprintf(buf, "%s{\n", funcTab)
+	fmt.Fprintf(buf, "%sif(case_ != other.case_) return case_ < other.case_;\n", bodyTab)
+	fmt.Fprintf(buf, "%sswitch(case_)\n", bodyTab)
+	fmt.Fprintf(buf, "%s{\n", bodyTab)
+	for _, field := range fields {
+		fmt.Fprintf(buf, "%scase %s: return u_.%s < other.u_.%s;\n", caseTab, oneofCase(field), field.GetName(), field.GetName())
+	}
+	fmt.Fprintf(buf, "%sdefault: return false;\n", caseTab)
+	fmt.Fprintf(buf, "%s}\n", bodyTab)
+	fmt.Fprintf(buf, "%s}\n\n", funcTab)
+
+	fmt.Fprintf(buf, "%sprivate:\n", currentTAB)
+	fmt.Fprintf(buf, "%svoid CopyFrom(const %s& other)\n", funcTab, name)
+	fmt.Fprintf(buf, "%s{\n", funcTab)
+	fmt.Fprintf(buf, "%sswitch(other.case_)\n", bodyTab)
+	fmt.Fprintf(buf, "%s{\n", bodyTab)
+	for _, field := range fields {
+		fmt.Fprintf(buf, "%scase %s: new (&u_.%s) %s_type(other.u_.%s); break;\n", caseTab, oneofCase(field), field.GetName(), field.GetName(), field.GetName())
+	}
+	fmt.Fprintf(buf, "%sdefault: break;\n", caseTab)
+	fmt.Fprintf(buf, "%s}\n", bodyTab)
+	fmt.Fprintf(buf, "%scase_ = other.case_;\n", bodyTab)
+	fmt.Fprintf(buf, "%s}\n", funcTab)
+	fmt.Fprintf(buf, "%sunion Storage\n", funcTab)
+	fmt.Fprintf(buf, "%s{\n", funcTab)
+	fmt.Fprintf(buf, "%sStorage() {}\n", bodyTab)
+	fmt.Fprintf(buf, "%s~Storage() {}\n", bodyTab)
+	for _, field := range fields {
+		fmt.Fprintf(buf, "%s%s_type %s;\n", bodyTab, field.GetName(), field.GetName())
+	}
+	fmt.Fprintf(buf, "%s};\n", funcTab)
+	fmt.Fprintf(buf, "%smmdata::CharAllocator alloc_;\n", funcTab)
+	fmt.Fprintf(buf, "%sCase case_;\n", funcTab)
+	fmt.Fprintf(buf, "%sStorage u_;\n", funcTab)
+	fmt.Fprintf(buf, "%s};\n", currentTAB)
+
+	instance := name + "<" + args + ">"
+	fmt.Fprintf(buf, "%stemplate<%s>\n", currentTAB, params)
+	fmt.Fprintf(buf, "%sinline std::size_t hash_value(const %s& v)\n", currentTAB, instance)
+	fmt.Fprintf(buf, "%s{\n", currentTAB)
+	fmt.Fprintf(buf, "%sstd::size_t hash = boost::hash_value(static_cast<int32_t>(v.which()));\n", funcTab)
+	fmt.Fprintf(buf, "%sswitch(v.which())\n", funcTab)
+	fmt.Fprintf(buf, "%s{\n", funcTab)
+	for _, field := range fields {
+		fmt.Fprintf(buf, "%scase %s::%s: hash ^= boost::hash<%s_type>()(v.%s()); break;\n", bodyTab, instance, oneofCase(field), field.GetName(), field.GetName())
+	}
+	fmt.Fprintf(buf, "%sdefault: break;\n", bodyTab)
+	fmt.Fprintf(buf, "%s}\n", funcTab)
+	fmt.Fprintf(buf, "%sreturn hash;\n", funcTab)
+	fmt.Fprintf(buf, "%s}\n", currentTAB)
+
+	fmt.Fprintf(buf, "%stemplate<%s>\n", currentTAB, params)
+	fmt.Fprintf(buf, "%sinline std::ostream& operator<<(std::ostream& os, const %s& v)\n", currentTAB, instance)
+	fmt.Fprintf(buf, "%s{\n", currentTAB)
+	fmt.Fprintf(buf, "%sos<<\"{\";\n", funcTab)
+	fmt.Fprintf(buf, "%sswitch(v.which())\n", funcTab)
+	fmt.Fprintf(buf, "%s{\n", funcTab)
+	for _, field := range fields {
+		fmt.Fprintf(buf, "%scase %s::%s: os<<\"%s=\"<<v.%s(); break;\n", bodyTab, instance, oneofCase(field), field.GetName(), field.GetName())
+	}
+	fmt.Fprintf(buf, "%sdefault: break;\n", bodyTab)
+	fmt.Fprintf(buf, "%s}\n", funcTab)
+	fmt.Fprintf(buf, "%sos<<\"}\";\n", funcTab)
+	fmt.Fprintf(buf, "%sreturn os;\n", funcTab)
+	fmt.Fprintf(buf, "%s}\n\n", currentTAB)
+}
+
+// dumpOneofKcfg maps a union to a json object with the member of the set
+// field, like {"name":"x"}, an empty object clears it.
+func (g *Generator) dumpOneofKcfg(buf *bytes.Buffer, msg *descriptor.DescriptorProto, index int, currentTAB string) {
+	fields := oneofFields(msg, index)
+	params, args := oneofParams(fields)
+	ns := ""
+	if t := g.registry.MessageOf(msg); nil != t {
+		ns = g.cppNamespace(t.Package())
+	}
+	instance := ns + "::" + g.oneofTypeName(msg, index) + "<" + args + ">"
+	funcTab := currentTAB + "    "
+	blockTab := funcTab + "    "
+
+	fmt.Fprintf(buf, "%stemplate<%s>\n", currentTAB, params)
+	fmt.Fprintf(buf, "%sinline bool Parse(const rapidjson::Value& json, const char* name, %s& v)\n", currentTAB, instance)
+	fmt.Fprintf(buf, "%s{\n", currentTAB)
+	fmt.Fprintf(buf, "%sconst rapidjson::Value* val = &json;\n", funcTab)
+	fmt.Fprintf(buf, "%sif(NULL != name && name[0] != 0)\n", funcTab)
+	fmt.Fprintf(buf, "%s{\n", funcTab)
+	fmt.Fprintf(buf, "%sif(!json.IsObject() || !json.HasMember(name)) return false;\n", blockTab)
+	fmt.Fprintf(buf, "%sval = &json[name];\n", blockTab)
+	fmt.Fprintf(buf, "%s}\n", funcTab)
+	fmt.Fprintf(buf, "%sif(!val->IsObject()) return false;\n", funcTab)
+	for _, field := range fields {
+		fmt.Fprintf(buf, "%sif(val->HasMember(\"%s\")) return Parse(*val, \"%s\", v.mutable_%s());\n", funcTab, field.GetName(), field.GetName(), field.GetName())
+	}
+	fmt.Fprintf(buf, "%sv.clear();\n", funcTab)
+	fmt.Fprintf(buf, "%sreturn true;\n", funcTab)
+	fmt.Fprintf(buf, "%s}\n", currentTAB)
+
+	fmt.Fprintf(buf, "%stemplate<%s>\n", currentTAB, params)
+	fmt.Fprintf(buf, "%sinline void Serialize(rapidjson::Value& json, rapidjson::Value::AllocatorType& allocator, const char* name, const %s& v)\n", currentTAB, instance)
+	fmt.Fprintf(buf, "%s{\n", currentTAB)
+	fmt.Fprintf(buf, "%srapidjson::Value val(rapidjson::kObjectType);\n", funcTab)
+	fmt.Fprintf(buf, "%sswitch(v.which())\n", funcTab)
+	fmt.Fprintf(buf, "%s{\n", funcTab)
+	for _, field := range fields {
+		fmt.Fprintf(buf, "%scase %s::%s: Serialize(val, allocator, \"%s\", v.%s()); break;\n", blockTab, instance, oneofCase(field), field.GetName(), field.GetName())
+	}
+	fmt.Fprintf(buf, "%sdefault: break;\n", blockTab)
+	fmt.Fprintf(buf, "%s}\n", funcTab)
+	fmt.Fprintf(buf, "%sif(NULL != name && name[0] != 0)\n", funcTab)
+	fmt.Fprintf(buf, "%s{\n", funcTab)
+	fmt.Fprintf(buf, "%sjson.AddMember(rapidjson::Value(name, allocator).Move(), val, allocator);\n", blockTab)
+	fmt.Fprintf(buf, "%s}\n", funcTab)
+	fmt.Fprintf(buf, "%selse\n", funcTab)
+	fmt.Fprintf(buf, "%s{\n", funcTab)
+	fmt.Fprintf(buf, "%sjson = val;\n", blockTab)
+	fmt.Fprintf(buf, "%s}\n", funcTab)
+	fmt.Fprintf(buf, "%s}\n", currentTAB)
+}
+
+// dumpOneofAccessors emits which_xxx() and the typed accessors of the fields
+// of the oneofs of a struct, forwarding to the unions.
+func (g *Generator) dumpOneofAccessors(buf *bytes.Buffer, msg *descriptor.DescriptorProto, currentTAB string) {
+	for _, i := range oneofIndexes(msg) {
+		oneof := msg.OneofDecl[i]
+		name := oneof.GetName()
+		fmt.Fprintf(buf, "\n%s%s::Case which_%s() const { return %s.which(); }\n", currentTAB, oneofLocalName(oneof), name, name)
+		for _, field := range oneofFields(msg, i) {
+			fname := field.GetName()
+			ftype := g.getBaseFieldType(field)
+			fmt.Fprintf(buf, "%sbool has_%s() const { return %s.has_%s(); }\n", currentTAB, fname, name, fname)
+			fmt.Fprintf(buf, "%sconst %s& %s() const { return %s.%s(); }\n", currentTAB, ftype, fname, name, fname)
+			fmt.Fprintf(buf, "%s%s& mutable_%s() { return %s.mutable_%s(); }\n", currentTAB, ftype, fname, name, fname)
+			fmt.Fprintf(buf, "%svoid set_%s(const %s& v) { this->%s.set_%s(v); }\n", currentTAB, fname, ftype, name, fname)
+		}
+	}
+}
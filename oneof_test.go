@@ -0,0 +1,30 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+//go:generate protoc -Itestdata -I. --include_imports --include_source_info --descriptor_set_out=testdata/oneofs.pb testdata/oneofs.proto
+//go:generate protoc -Itestdata -I. --include_imports --include_source_info --descriptor_set_out=testdata/oneofs_errors.pb testdata/oneofs_errors.proto
+
+func TestOneofs(t *testing.T) {
+	header := testGenerate(t, "oneofs", "")["oneofs.proto.hpp"]
+	for _, want := range []string{
+		"template<typename num_type, typename text_type, typename item_type, typename sub_type, typename color_type>\n        struct Entry_ChoiceOneof\n",
+		"                CHOICE_NOT_SET = 0,\n                kNum = 3,\n                kText = 4,\n                kItem = 5,\n                kSub = 6,\n                kColor = 7,\n",
+		//members taking the allocator are constructed with it
+		"new (&u_.num) num_type();",
+		"new (&u_.text) text_type(alloc_);",
+		"new (&u_.item) item_type(alloc_);",
+		"case kItem: visitor(u_.item); return true;",
+		"if(val->HasMember(\"text\")) return Parse(*val, \"text\", v.mutable_text());",
+		"typedef Entry_ChoiceOneof<int64_t, mmdata::SHMString, Item, Entry::Sub, Color> ChoiceOneof;",
+		"            ChoiceOneof choice;\n",
+		"Entry(const mmdata::CharAllocator& alloc):key(alloc),before(0),choice(alloc),val(alloc)",
+	} {
+		if !strings.Contains(header, want) {
+			t.Errorf("oneofs.proto.hpp does not contain %q:\n%s", want, header)
+		}
+	}
+}
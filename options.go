@@ -0,0 +1,343 @@
+package main
+
+//go:generate protoc -I. -I$PROTOBUF_INCLUDE --go_out=import_path=main:. mmdata_base.proto
+
+import (
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// E_FieldContainer/E_MessageContainer are the "Tree"/"Hash" container option
+// of the first releases, which is not in mmdata_base.proto but declared by the
+// schemas themselves. It is still accepted next to the enum options:
+//
+//	extend google.protobuf.FieldOptions { string Container = 51236; }
+//	extend google.protobuf.MessageOptions { string Container = 51236; }
+var E_FieldContainer = &proto.ExtensionDesc{
+	ExtendedType:  (*descriptor.FieldOptions)(nil),
+	ExtensionType: (*string)(nil),
+	Field:         51236,
+	Name:          "Container",
+	Tag:           "bytes,51236,opt,name=Container",
+}
+
+var E_MessageContainer = &proto.ExtensionDesc{
+	ExtendedType:  (*descriptor.MessageOptions)(nil),
+	ExtensionType: (*string)(nil),
+	Field:         51236,
+	Name:          "Container",
+	Tag:           "bytes,51236,opt,name=Container",
+}
+
+// ContainerKind selects the container of a root table or a map field.
+type ContainerKind int
+
+const (
+	ContainerHash ContainerKind = iota
+	ContainerTree
+	ContainerFlatSorted
+	ContainerFlat
+	ContainerPerfectHash
+)
+
+func (k ContainerKind) String() string {
+	return Mmdata_ContainerKind(k).String()
+}
+
+// TableKind selects whether a root table holds several entries per key.
+type TableKind int
+
+const (
+	TableUnique TableKind = iota
+	TableMulti
+)
+
+// DuplicateKey selects what the Insert() of a root table does with the
+// entries of a key already inserted.
+type DuplicateKey int
+
+const (
+	DuplicateKeepFirst DuplicateKey = iota
+	DuplicateKeepLast
+	DuplicateMerge
+	DuplicateFailBuild
+)
+
+func (k DuplicateKey) String() string {
+	return Mmdata_DuplicateKey(k).String()
+}
+
+// MessageOptions is the mmdata options of a message.
+type MessageOptions struct {
+	Container ContainerKind
+	//whether (mmdata.container) or Container is given explicitly
+	HasContainer bool
+	//(mmdata.all_values)
+	AllValues bool
+	//(mmdata.table)
+	Table    TableKind
+	HasTable bool
+	//(mmdata.duplicate_key)
+	DuplicateKey    DuplicateKey
+	HasDuplicateKey bool
+	//(mmdata.eytzinger)
+	Eytzinger bool
+}
+
+// FieldOptions is the mmdata options of a field.
+type FieldOptions struct {
+	Key       bool
+	Value     bool
+	Container ContainerKind
+	//whether (mmdata.map_container) or Container is given explicitly
+	HasContainer bool
+	//(mmdata.default), see defaultText for the proto2 default too
+	Default    string
+	HasDefault bool
+	//(mmdata.cpp_type), empty for the type mapped from the proto type
+	CppType        string
+	CppTypeAlloc   bool
+	CppTypeInclude string
+	//(mmdata.fixed_size), 0 for the allocated containers
+	FixedSize     uint32
+	FixedTruncate bool
+	//whether (mmdata.fixed_overflow) is given explicitly
+	HasFixedOverflow bool
+	//(mmdata.key_order) of a field of a composite key
+	KeyOrder    uint32
+	HasKeyOrder bool
+	//(mmdata.index), empty for no index
+	Index       string
+	UniqueIndex bool
+}
+
+func parseContainerKind(v string) (ContainerKind, bool) {
+	switch {
+	case len(v) == 0, strings.EqualFold(v, "Hash"):
+		return ContainerHash, true
+	case strings.EqualFold(v, "Tree"):
+		return ContainerTree, true
+	}
+	return ContainerHash, false
+}
+
+func toContainerKind(v Mmdata_ContainerKind) (ContainerKind, bool) {
+	switch v {
+	case Mmdata_HASH:
+		return ContainerHash, true
+	case Mmdata_TREE:
+		return ContainerTree, true
+	case Mmdata_FLAT_SORTED:
+		return ContainerFlatSorted, true
+	case Mmdata_FLAT:
+		return ContainerFlat, true
+	case Mmdata_PERFECT_HASH:
+		return ContainerPerfectHash, true
+	}
+	return ContainerHash, false
+}
+
+func (g *Generator) messageOptions(msg *descriptor.DescriptorProto) *MessageOptions {
+	if opts, exist := g.msgOptions[msg]; exist {
+		return opts
+	}
+	if nil == g.msgOptions {
+		g.msgOptions = make(map[*descriptor.DescriptorProto]*MessageOptions)
+	}
+	opts := &MessageOptions{}
+	g.msgOptions[msg] = opts
+	if nil == msg.GetOptions() {
+		return opts
+	}
+	if proto.HasExtension(msg.GetOptions(), E_MessageContainer) {
+		v, err := proto.GetExtension(msg.GetOptions(), E_MessageContainer)
+		if err != nil {
+			g.messageError(msg, "Invalid option Container:%v", err)
+		} else if kind, ok := parseContainerKind(*v.(*string)); ok {
+			opts.Container = kind
+			opts.HasContainer = true
+		} else {
+			g.messageError(msg, "Invalid option Container:%s, expected Hash or Tree", *v.(*string))
+		}
+	}
+	if proto.HasExtension(msg.GetOptions(), E_Mmdata_Container) {
+		if opts.HasContainer {
+			g.messageError(msg, "Option Container and (mmdata.container) are both given, keep (mmdata.container) only")
+		}
+		v, err := proto.GetExtension(msg.GetOptions(), E_Mmdata_Container)
+		if err != nil {
+			g.messageError(msg, "Invalid option (mmdata.container):%v", err)
+		} else if kind, ok := toContainerKind(*v.(*Mmdata_ContainerKind)); ok {
+			opts.Container = kind
+			opts.HasContainer = true
+		} else {
+			g.messageError(msg, "Invalid option (mmdata.container):%d", *v.(*Mmdata_ContainerKind))
+		}
+	}
+	if proto.HasExtension(msg.GetOptions(), E_Mmdata_AllValues) {
+		v, err := proto.GetExtension(msg.GetOptions(), E_Mmdata_AllValues)
+		if err != nil {
+			g.messageError(msg, "Invalid option (mmdata.all_values):%v", err)
+		} else {
+			opts.AllValues = *v.(*bool)
+		}
+	}
+	if proto.HasExtension(msg.GetOptions(), E_Mmdata_Eytzinger) {
+		v, err := proto.GetExtension(msg.GetOptions(), E_Mmdata_Eytzinger)
+		if err != nil {
+			g.messageError(msg, "Invalid option (mmdata.eytzinger):%v", err)
+		} else {
+			opts.Eytzinger = *v.(*bool)
+		}
+	}
+	if proto.HasExtension(msg.GetOptions(), E_Mmdata_Table) {
+		v, err := proto.GetExtension(msg.GetOptions(), E_Mmdata_Table)
+		if err != nil {
+			g.messageError(msg, "Invalid option (mmdata.table):%v", err)
+		} else {
+			switch *v.(*Mmdata_TableKind) {
+			case Mmdata_UNIQUE:
+				opts.Table = TableUnique
+			case Mmdata_MULTI:
+				opts.Table = TableMulti
+			default:
+				g.messageError(msg, "Invalid option (mmdata.table):%d", *v.(*Mmdata_TableKind))
+			}
+			opts.HasTable = true
+		}
+	}
+	if proto.HasExtension(msg.GetOptions(), E_Mmdata_DuplicateKey) {
+		v, err := proto.GetExtension(msg.GetOptions(), E_Mmdata_DuplicateKey)
+		if err != nil {
+			g.messageError(msg, "Invalid option (mmdata.duplicate_key):%v", err)
+		} else if k := *v.(*Mmdata_DuplicateKey); k < Mmdata_KEEP_FIRST || k > Mmdata_FAIL_BUILD {
+			g.messageError(msg, "Invalid option (mmdata.duplicate_key):%d", k)
+		} else {
+			opts.DuplicateKey = DuplicateKey(k)
+			opts.HasDuplicateKey = true
+		}
+	}
+	return opts
+}
+
+func (g *Generator) fieldOptions(field *descriptor.FieldDescriptorProto) *FieldOptions {
+	if opts, exist := g.fieldOpts[field]; exist {
+		return opts
+	}
+	if nil == g.fieldOpts {
+		g.fieldOpts = make(map[*descriptor.FieldDescriptorProto]*FieldOptions)
+	}
+	opts := &FieldOptions{}
+	g.fieldOpts[field] = opts
+	if nil == field.GetOptions() {
+		return opts
+	}
+	opts.Key = g.boolFieldOption(field, E_Key)
+	opts.Value = g.boolFieldOption(field, E_Value)
+	if proto.HasExtension(field.GetOptions(), E_FieldContainer) {
+		v, err := proto.GetExtension(field.GetOptions(), E_FieldContainer)
+		if err != nil {
+			g.fieldError(field, "Invalid option Container:%v", err)
+		} else if kind, ok := parseContainerKind(*v.(*string)); ok {
+			opts.Container = kind
+			opts.HasContainer = true
+		} else {
+			g.fieldError(field, "Invalid option Container:%s, expected Hash or Tree", *v.(*string))
+		}
+	}
+	if proto.HasExtension(field.GetOptions(), E_Mmdata_MapContainer) {
+		if opts.HasContainer {
+			g.fieldError(field, "Option Container and (mmdata.map_container) are both given, keep (mmdata.map_container) only")
+		}
+		v, err := proto.GetExtension(field.GetOptions(), E_Mmdata_MapContainer)
+		if err != nil {
+			g.fieldError(field, "Invalid option (mmdata.map_container):%v", err)
+		} else if kind, ok := toContainerKind(*v.(*Mmdata_ContainerKind)); ok {
+			opts.Container = kind
+			opts.HasContainer = true
+		} else {
+			g.fieldError(field, "Invalid option (mmdata.map_container):%d", *v.(*Mmdata_ContainerKind))
+		}
+		if opts.HasContainer && !g.isMapField(field) {
+			g.fieldError(field, "Option (mmdata.map_container) is only for map fields")
+		} else if opts.Container == ContainerFlat || opts.Container == ContainerPerfectHash {
+			g.fieldError(field, "Option (mmdata.map_container) = %v is only for root tables, use FLAT_SORTED", opts.Container)
+		}
+	}
+	if proto.HasExtension(field.GetOptions(), E_Mmdata_Default) {
+		v, err := proto.GetExtension(field.GetOptions(), E_Mmdata_Default)
+		if err != nil {
+			g.fieldError(field, "Invalid option (mmdata.default):%v", err)
+		} else {
+			opts.Default = *v.(*string)
+			opts.HasDefault = true
+		}
+	}
+	if proto.HasExtension(field.GetOptions(), E_Mmdata_CppType) {
+		v, err := proto.GetExtension(field.GetOptions(), E_Mmdata_CppType)
+		if err != nil {
+			g.fieldError(field, "Invalid option (mmdata.cpp_type):%v", err)
+		} else {
+			t := v.(*Mmdata_CppType)
+			opts.CppType = strings.TrimSpace(t.GetName())
+			opts.CppTypeAlloc = t.GetAllocator()
+			opts.CppTypeInclude = t.GetInclude()
+			if len(opts.CppType) == 0 {
+				g.fieldError(field, "Invalid option (mmdata.cpp_type):missing name")
+			}
+		}
+	}
+	if proto.HasExtension(field.GetOptions(), E_Mmdata_FixedSize) {
+		v, err := proto.GetExtension(field.GetOptions(), E_Mmdata_FixedSize)
+		if err != nil {
+			g.fieldError(field, "Invalid option (mmdata.fixed_size):%v", err)
+		} else if opts.FixedSize = *v.(*uint32); opts.FixedSize == 0 {
+			g.fieldError(field, "Invalid option (mmdata.fixed_size):0")
+		}
+	}
+	if proto.HasExtension(field.GetOptions(), E_Mmdata_FixedOverflow) {
+		v, err := proto.GetExtension(field.GetOptions(), E_Mmdata_FixedOverflow)
+		if err != nil {
+			g.fieldError(field, "Invalid option (mmdata.fixed_overflow):%v", err)
+		} else {
+			opts.FixedTruncate = *v.(*Mmdata_FixedOverflow) == Mmdata_TRUNCATE
+			opts.HasFixedOverflow = true
+		}
+	}
+	if proto.HasExtension(field.GetOptions(), E_Mmdata_KeyOrder) {
+		v, err := proto.GetExtension(field.GetOptions(), E_Mmdata_KeyOrder)
+		if err != nil {
+			g.fieldError(field, "Invalid option (mmdata.key_order):%v", err)
+		} else {
+			opts.KeyOrder = *v.(*uint32)
+			opts.HasKeyOrder = true
+		}
+	}
+	if proto.HasExtension(field.GetOptions(), E_Mmdata_Index) {
+		v, err := proto.GetExtension(field.GetOptions(), E_Mmdata_Index)
+		if err != nil {
+			g.fieldError(field, "Invalid option (mmdata.index):%v", err)
+		} else if opts.Index = *v.(*string); len(opts.Index) == 0 {
+			g.fieldError(field, "Invalid option (mmdata.index):missing name")
+		}
+	}
+	opts.UniqueIndex = g.boolFieldOption(field, E_Mmdata_UniqueIndex)
+	if opts.UniqueIndex && len(opts.Index) == 0 {
+		g.fieldError(field, "Option (mmdata.unique_index) is only for fields with (mmdata.index)")
+	}
+	return opts
+}
+
+func (g *Generator) boolFieldOption(field *descriptor.FieldDescriptorProto, ext *proto.ExtensionDesc) bool {
+	if !proto.HasExtension(field.GetOptions(), ext) {
+		return false
+	}
+	v, err := proto.GetExtension(field.GetOptions(), ext)
+	if err != nil {
+		g.fieldError(field, "Invalid option %s:%v", ext.Name, err)
+		return false
+	}
+	return *v.(*bool)
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+//go:generate protoc -Itestdata -I. --include_imports --include_source_info --descriptor_set_out=testdata/legacy.pb testdata/legacy.proto
+//go:generate protoc -Itestdata -I. --include_imports --include_source_info --descriptor_set_out=testdata/legacy_errors.pb testdata/legacy_errors.proto
+//go:generate protoc -Itestdata -I. --include_imports --include_source_info --descriptor_set_out=testdata/containers.pb testdata/containers.proto
+//go:generate protoc -Itestdata -I. --include_imports --include_source_info --descriptor_set_out=testdata/containers_errors.pb testdata/containers_errors.proto
+
+// TestLegacyContainer checks the Container option of the schemas of the
+// first releases, declared by the schemas themselves next to the import of
+// mmdata_base.proto.
+func TestLegacyContainer(t *testing.T) {
+	header := testGenerate(t, "legacy", "")["legacy.proto.hpp"]
+	for _, want := range []string{
+		"typedef mmdata::SHMMap<mmdata::SHMString, int64_t>::Type value_type;",
+		"typedef mmdata::SHMMap<mmdata::SHMString, mmdata::SHMMap<mmdata::SHMString, int64_t>::Type>::Type TreeEntryTableParent;",
+		"typedef mmdata::SHMHashMap<int64_t, mmdata::SHMString>::Type HashEntryTableParent;",
+		//(Key) = false is not a key
+		"            mmdata::SHMString other;\n",
+	} {
+		if !strings.Contains(header, want) {
+			t.Errorf("legacy.proto.hpp does not contain %q:\n%s", want, header)
+		}
+	}
+}
+
+func TestContainerOptions(t *testing.T) {
+	header := testGenerate(t, "containers", "")["containers.proto.hpp"]
+	for _, want := range []string{
+		"typedef mmdata::SHMMap<mmdata::SHMString, mmdata::SHMFlatMap<mmdata::SHMString, int64_t>::Type>::Type TreeEntryTableParent;",
+		"typedef mmdata::SHMHashMap<int64_t, mmdata::SHMMap<int64_t, mmdata::SHMString>::Type>::Type HashEntryTableParent;",
+	} {
+		if !strings.Contains(header, want) {
+			t.Errorf("containers.proto.hpp does not contain %q:\n%s", want, header)
+		}
+	}
+}
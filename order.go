@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// typeDep is a message type used by a field. A hard dependency needs the
+// complete type, while a SHMVector only needs a forward declaration.
+type typeDep struct {
+	name string
+	hard bool
+}
+
+func (g *Generator) fieldDeps(field *descriptor.FieldDescriptorProto) []typeDep {
+	if entry := g.mapEntry(field); nil != entry {
+		var deps []typeDep
+		for _, f := range entry.Field {
+			if f.GetType() == descriptor.FieldDescriptorProto_TYPE_MESSAGE {
+				deps = append(deps, typeDep{name: f.GetTypeName(), hard: true})
+			}
+		}
+		return deps
+	}
+	if field.GetType() != descriptor.FieldDescriptorProto_TYPE_MESSAGE {
+		return nil
+	}
+	return []typeDep{{name: field.GetTypeName(), hard: field.GetLabel() != descriptor.FieldDescriptorProto_LABEL_REPEATED}}
+}
+
+// messageDeps returns the types used by a message, in nested struct mode
+// including the ones used by its nested messages.
+func (g *Generator) messageDeps(msg *descriptor.DescriptorProto) []typeDep {
+	var deps []typeDep
+	for _, field := range msg.Field {
+		deps = append(deps, g.fieldDeps(field)...)
+	}
+	for _, nest := range nestedTypes(msg) {
+		if g.config.Nested == nestedMangle {
+			//the typedef in the parent only needs a declaration
+			t := g.registry.MessageOf(nest)
+			if nil != t {
+				deps = append(deps, typeDep{name: t.FullName})
+			}
+		} else {
+			deps = append(deps, g.messageDeps(nest)...)
+		}
+	}
+	return deps
+}
+
+// sortMessages orders sibling messages so that every message comes after the
+// messages it uses, keeping the declaration order otherwise. It also returns
+// the messages to forward declare before each one, for the SHMVector fields
+// of messages used by each other.
+func (g *Generator) sortMessages(msgs []*descriptor.DescriptorProto) ([]*descriptor.DescriptorProto, map[*descriptor.DescriptorProto][]*descriptor.DescriptorProto) {
+	//maps the type names of every message (and its nested ones in nested
+	//struct mode) to the index of its sibling
+	units := make(map[string]int)
+	names := make([]string, len(msgs))
+	for i, msg := range msgs {
+		t := g.registry.MessageOf(msg)
+		if nil == t {
+			continue
+		}
+		names[i] = t.FullName
+		units[t.FullName] = i
+		if g.config.Nested != nestedMangle {
+			for _, name := range nestedNames(t.FullName, msg) {
+				units[name] = i
+			}
+		}
+	}
+
+	hard := make([]map[int]bool, len(msgs))
+	soft := make([]map[int]bool, len(msgs))
+	for i, msg := range msgs {
+		hard[i] = make(map[int]bool)
+		soft[i] = make(map[int]bool)
+		for _, dep := range g.messageDeps(msg) {
+			j, exist := units[dep.name]
+			if !exist {
+				continue
+			}
+			if j == i {
+				//the message is declared in its own body, only a by-value
+				//use of itself is impossible
+				if dep.hard && dep.name == names[i] {
+					hard[i][j] = true
+				}
+				continue
+			}
+			//a nested struct can not be forward declared out of its parent
+			if dep.hard || dep.name != names[j] {
+				hard[i][j] = true
+			} else {
+				soft[i][j] = true
+			}
+		}
+	}
+
+	emitted := make([]bool, len(msgs))
+	declared := make([]bool, len(msgs))
+	forwards := make(map[*descriptor.DescriptorProto][]*descriptor.DescriptorProto)
+	var sorted []*descriptor.DescriptorProto
+	ready := func(i int, deps map[int]bool) bool {
+		for j := range deps {
+			if !emitted[j] {
+				return false
+			}
+		}
+		return true
+	}
+	for len(sorted) < len(msgs) {
+		pick := -1
+		for i := range msgs {
+			if !emitted[i] && ready(i, hard[i]) && ready(i, soft[i]) {
+				pick = i
+				break
+			}
+		}
+		if pick < 0 {
+			for i := range msgs {
+				if !emitted[i] && ready(i, hard[i]) {
+					pick = i
+					break
+				}
+			}
+			if pick >= 0 {
+				for j := range msgs {
+					if soft[pick][j] && !emitted[j] && !declared[j] {
+						forwards[msgs[pick]] = append(forwards[msgs[pick]], msgs[j])
+						declared[j] = true
+					}
+				}
+			}
+		}
+		if pick < 0 {
+			//report the cycle and go on with the others
+			for _, i := range g.reportCycle(msgs, names, hard, emitted) {
+				emitted[i] = true
+				sorted = append(sorted, msgs[i])
+			}
+			continue
+		}
+		emitted[pick] = true
+		sorted = append(sorted, msgs[pick])
+	}
+	return sorted, forwards
+}
+
+// reportCycle reports a cycle of by-value uses among the messages not emitted,
+// and returns the messages of the cycle.
+func (g *Generator) reportCycle(msgs []*descriptor.DescriptorProto, names []string, hard []map[int]bool, emitted []bool) []int {
+	start := -1
+	for i := range msgs {
+		if !emitted[i] {
+			start = i
+			break
+		}
+	}
+	//every remaining message uses another remaining one, so walking the uses
+	//ends in a cycle
+	visited := make(map[int]int)
+	var path []int
+	i := start
+	for {
+		if pos, exist := visited[i]; exist {
+			path = path[pos:]
+			break
+		}
+		visited[i] = len(path)
+		path = append(path, i)
+		next := -1
+		for j := range msgs {
+			if hard[i][j] && !emitted[j] {
+				next = j
+				break
+			}
+		}
+		i = next
+	}
+	var cycle []string
+	for _, i := range path {
+		cycle = append(cycle, strings.TrimPrefix(names[i], "."))
+	}
+	cycle = append(cycle, cycle[0])
+	g.messageError(msgs[path[0]], "%s is a by-value cycle which can not be laid out in shared memory", strings.Join(cycle, " -> "))
+	return path
+}
+
+type messageOrder struct {
+	sorted   []*descriptor.DescriptorProto
+	forwards map[*descriptor.DescriptorProto][]*descriptor.DescriptorProto
+}
+
+// sortNestedTypes sorts the nested messages of a message once, as they are
+// walked for the struct and for the printers.
+func (g *Generator) sortNestedTypes(msg *descriptor.DescriptorProto) ([]*descriptor.DescriptorProto, map[*descriptor.DescriptorProto][]*descriptor.DescriptorProto) {
+	if order, exist := g.nestedOrder[msg]; exist {
+		return order.sorted, order.forwards
+	}
+	if nil == g.nestedOrder {
+		g.nestedOrder = make(map[*descriptor.DescriptorProto]messageOrder)
+	}
+	sorted, forwards := g.sortMessages(nestedTypes(msg))
+	g.nestedOrder[msg] = messageOrder{sorted: sorted, forwards: forwards}
+	return sorted, forwards
+}
+
+// DumpMessages emits the messages of a file in dependency order, in mangled
+// mode the nested messages are emitted as top-level ones. The kcfg mapping of
+// messages tracking presence follows each one, before the messages using it.
+func (g *Generator) DumpMessages(file *descriptor.FileDescriptorProto, currentTAB string) {
+	msgs := file.MessageType
+	if g.config.Nested == nestedMangle {
+		msgs = nil
+		for _, msg := range file.MessageType {
+			msgs = append(msgs, flattenMessages(msg)...)
+		}
+	}
+	sorted, forwards := g.sortMessages(msgs)
+	for _, msg := range sorted {
+		g.dumpForwards(&g.OutputBuffer, forwards[msg], currentTAB)
+		g.DumpMessage(msg, currentTAB)
+		g.dumpPresenceKcfg(msg)
+	}
+}
+
+func (g *Generator) dumpForwards(buf *bytes.Buffer, msgs []*descriptor.DescriptorProto, currentTAB string) {
+	for _, msg := range msgs {
+		fmt.Fprintf(buf, "%sstruct %s;\n", currentTAB, g.structName(msg))
+	}
+}
+
+// nestedNames returns the full names of all messages nested in a message.
+func nestedNames(fullName string, msg *descriptor.DescriptorProto) []string {
+	var names []string
+	for _, nest := range msg.NestedType {
+		name := fullName + "." + nest.GetName()
+		names = append(names, name)
+		names = append(names, nestedNames(name, nest)...)
+	}
+	return names
+}
+
+// flattenMessages returns a message after its nested messages.
+func flattenMessages(msg *descriptor.DescriptorProto) []*descriptor.DescriptorProto {
+	var msgs []*descriptor.DescriptorProto
+	for _, nest := range nestedTypes(msg) {
+		msgs = append(msgs, flattenMessages(nest)...)
+	}
+	return append(msgs, msg)
+}
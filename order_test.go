@@ -0,0 +1,150 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// testMessage returns a message of fields, with nested messages.
+func testMessage(name string, fields []*descriptor.FieldDescriptorProto, nested ...*descriptor.DescriptorProto) *descriptor.DescriptorProto {
+	return &descriptor.DescriptorProto{Name: proto.String(name), Field: fields, NestedType: nested}
+}
+
+// testField returns a field of a scalar type, or of the message typeName of
+// package demo if not empty.
+func testField(name string, number int32, typeName string, repeated bool) *descriptor.FieldDescriptorProto {
+	field := &descriptor.FieldDescriptorProto{
+		Name:   proto.String(name),
+		Number: proto.Int32(number),
+		Label:  descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		Type:   descriptor.FieldDescriptorProto_TYPE_INT64.Enum(),
+	}
+	if repeated {
+		field.Label = descriptor.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	}
+	if len(typeName) > 0 {
+		field.Type = descriptor.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+		field.TypeName = proto.String(".demo." + typeName)
+	}
+	return field
+}
+
+// testGenerator returns a Generator of a file of package demo holding msgs.
+func testGenerator(config *Config, msgs ...*descriptor.DescriptorProto) (*Generator, *descriptor.FileDescriptorProto) {
+	file := &descriptor.FileDescriptorProto{
+		Name:        proto.String("test.proto"),
+		Package:     proto.String("demo"),
+		Syntax:      proto.String("proto3"),
+		MessageType: msgs,
+	}
+	g := &Generator{config: config, registry: NewRegistry([]*descriptor.FileDescriptorProto{file}), diag: &Diagnostics{}}
+	return g, file
+}
+
+func messageNames(msgs []*descriptor.DescriptorProto) []string {
+	var names []string
+	for _, msg := range msgs {
+		names = append(names, msg.GetName())
+	}
+	return names
+}
+
+func TestSortMessages(t *testing.T) {
+	tests := []struct {
+		name     string
+		msgs     []*descriptor.DescriptorProto
+		nested   string
+		want     []string
+		forwards map[string][]string
+		err      string
+	}{
+		{
+			name: "declaration order",
+			msgs: []*descriptor.DescriptorProto{testMessage("A", nil), testMessage("B", nil)},
+			want: []string{"A", "B"},
+		},
+		{
+			name: "used by value first",
+			msgs: []*descriptor.DescriptorProto{
+				testMessage("A", []*descriptor.FieldDescriptorProto{testField("b", 1, "B", false)}),
+				testMessage("B", []*descriptor.FieldDescriptorProto{testField("c", 1, "C", false)}),
+				testMessage("C", nil),
+			},
+			want: []string{"C", "B", "A"},
+		},
+		{
+			name: "repeated uses forward declared",
+			msgs: []*descriptor.DescriptorProto{
+				testMessage("A", []*descriptor.FieldDescriptorProto{testField("b", 1, "B", true)}),
+				testMessage("B", []*descriptor.FieldDescriptorProto{testField("a", 1, "A", true)}),
+			},
+			want:     []string{"A", "B"},
+			forwards: map[string][]string{"A": {"B"}},
+		},
+		{
+			name: "repeated self use",
+			msgs: []*descriptor.DescriptorProto{testMessage("Tree", []*descriptor.FieldDescriptorProto{testField("children", 1, "Tree", true)})},
+			want: []string{"Tree"},
+		},
+		{
+			name: "nested struct used by value",
+			msgs: []*descriptor.DescriptorProto{
+				testMessage("A", []*descriptor.FieldDescriptorProto{testField("in", 1, "B.In", true)}),
+				testMessage("B", nil, testMessage("In", nil)),
+			},
+			want: []string{"B", "A"},
+		},
+		{
+			name: "nested struct mangled",
+			msgs: []*descriptor.DescriptorProto{
+				testMessage("A", []*descriptor.FieldDescriptorProto{testField("in", 1, "B.In", true)}),
+				testMessage("B", nil, testMessage("In", nil)),
+			},
+			nested: nestedMangle,
+			want:   []string{"A", "B"},
+		},
+		{
+			name: "by-value cycle",
+			msgs: []*descriptor.DescriptorProto{
+				testMessage("A", []*descriptor.FieldDescriptorProto{testField("b", 1, "B", false)}),
+				testMessage("B", []*descriptor.FieldDescriptorProto{testField("a", 1, "A", false)}),
+				testMessage("C", nil),
+			},
+			want: []string{"C", "A", "B"},
+			err:  "test.proto: demo.A: demo.A -> demo.B -> demo.A is a by-value cycle which can not be laid out in shared memory",
+		},
+		{
+			name: "by-value self use",
+			msgs: []*descriptor.DescriptorProto{testMessage("S", []*descriptor.FieldDescriptorProto{testField("s", 1, "S", false)})},
+			want: []string{"S"},
+			err:  "test.proto: demo.S: demo.S -> demo.S is a by-value cycle which can not be laid out in shared memory",
+		},
+	}
+	for _, test := range tests {
+		config := DefaultConfig()
+		if len(test.nested) > 0 {
+			config.Nested = test.nested
+		}
+		g, file := testGenerator(config, test.msgs...)
+		sorted, forwards := g.sortMessages(file.MessageType)
+		if got := messageNames(sorted); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: sorted %v, want %v", test.name, got, test.want)
+		}
+		gotForwards := make(map[string][]string)
+		for msg, declared := range forwards {
+			gotForwards[msg.GetName()] = messageNames(declared)
+		}
+		if nil == test.forwards {
+			test.forwards = make(map[string][]string)
+		}
+		if !reflect.DeepEqual(gotForwards, test.forwards) {
+			t.Errorf("%s: forwards %v, want %v", test.name, gotForwards, test.forwards)
+		}
+		if got := g.diag.Error(); got != test.err {
+			t.Errorf("%s: error %q, want %q", test.name, got, test.err)
+		}
+	}
+}
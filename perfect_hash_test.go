@@ -0,0 +1,43 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+//go:generate protoc -Itestdata -I. --include_imports --include_source_info --descriptor_set_out=testdata/perfect_hash.pb testdata/perfect_hash.proto
+//go:generate protoc -Itestdata -I. --include_imports --include_source_info --descriptor_set_out=testdata/perfect_hash_errors.pb testdata/perfect_hash_errors.proto
+
+func TestPerfectHashTables(t *testing.T) {
+	files := testGenerate(t, "perfect_hash", "")
+	header := files["perfect_hash.proto.hpp"]
+	for _, want := range []string{
+		"struct PerfectHashPlan\n",
+		"PerfectHashPlan* perfect_hash;",
+		"typedef mmdata::pb::PerfectHashTable<int64_t, Item> NumTableParent;",
+		"typedef mmdata::pb::PerfectHashTable<Comp::key_type, int32_t> CompTableParent;",
+		"bool Insert(const Num& entry)\n            {\n                if(!Hashed(entry.GetKey())) return false;\n                if(code_index.find(entry.v.code) != code_index.end())",
+		"bool Insert(const Str& entry)\n            {\n                if(!Hashed(entry.GetKey())) return false;\n",
+	} {
+		if !strings.Contains(header, want) {
+			t.Errorf("perfect_hash.proto.hpp does not contain %q:\n%s", want, header)
+		}
+	}
+	source := files["perfect_hash.proto.cpp"]
+	for _, want := range []string{
+		"int64_t ret = builder.template Build<Num>(options);\n                if(ret >= 0)\n                {\n                    plan.Prepare();\n                    ret = builder.template Build<Num>(options);\n                }\n",
+		"report<<plan.misses<<\" entries dropped in Str with a key out of the perfect hash\";",
+	} {
+		if !strings.Contains(source, want) {
+			t.Errorf("perfect_hash.proto.cpp does not contain %q:\n%s", want, source)
+		}
+	}
+	//the hash is in the table once the second build is done
+	if strings.Contains(source, "Finish") {
+		t.Errorf("perfect_hash.proto.cpp finishes the tables after the build:\n%s", source)
+	}
+}
+
+func TestPerfectHashTablesBuild(t *testing.T) {
+	testCppChecks(t, "perfect_hash")
+}
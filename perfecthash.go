@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// verifyPerfectHash reports the options of a root table not supported by the
+// (mmdata.container) = PERFECT_HASH tables.
+func (g *Generator) verifyPerfectHash(msg *descriptor.DescriptorProto) {
+	msgOpts := g.messageOptions(msg)
+	if msgOpts.Container == ContainerPerfectHash && msgOpts.Table == TableMulti {
+		g.messageError(msg, "Option (mmdata.container) = PERFECT_HASH is not supported for tables with (mmdata.table) = MULTI")
+	}
+}
+
+// DumpPerfectHashTypes emits mmdata::pb::PerfectHashTable if a root table of
+// the file is a PERFECT_HASH one.
+func (g *Generator) DumpPerfectHashTypes(file *descriptor.FileDescriptorProto) {
+	for _, msg := range file.MessageType {
+		if _, haveKeyFiled := g.hashEntryMessages[msg]; haveKeyFiled && g.messageOptions(msg).Container == ContainerPerfectHash {
+			fmt.Fprintf(&g.OutputBuffer, "%s\n", perfectHashTypesCode)
+			return
+		}
+	}
+}
+
+// dumpPerfectHashInsert emits the first statement of the Insert of a
+// PERFECT_HASH table, which gives the key to the plan of the hash during the
+// first build, and drops the entries with no position in the hash.
+func (g *Generator) dumpPerfectHashInsert(buf *bytes.Buffer, msg *descriptor.DescriptorProto, currentTAB string) {
+	if g.messageOptions(msg).Container != ContainerPerfectHash {
+		return
+	}
+	fmt.Fprintf(buf, "%sif(!Hashed(entry.GetKey())) return false;\n", currentTAB)
+}
+
+// dumpPerfectHashBuild emits the two builds of a PERFECT_HASH table in the
+// Build helper: the first one reads the keys of the entries, the hash is
+// computed from them, and the second one inserts the entries.
+func (g *Generator) dumpPerfectHashBuild(buf *bytes.Buffer, msg *descriptor.DescriptorProto, currentTAB string) {
+	fmt.Fprintf(buf, "%smmdata::pb::PerfectHashPlan plan;\n", currentTAB)
+	fmt.Fprintf(buf, "%sctx.perfect_hash = &plan;\n", currentTAB)
+	fmt.Fprintf(buf, "%sint64_t ret = builder.template Build<%s>(options);\n", currentTAB, msg.GetName())
+	fmt.Fprintf(buf, "%sif(ret >= 0)\n", currentTAB)
+	fmt.Fprintf(buf, "%s{\n", currentTAB)
+	fmt.Fprintf(buf, "%s    plan.Prepare();\n", currentTAB)
+	fmt.Fprintf(buf, "%s    ret = builder.template Build<%s>(options);\n", currentTAB, msg.GetName())
+	fmt.Fprintf(buf, "%s}\n", currentTAB)
+}
+
+// dumpPerfectHashReport emits the report of the entries dropped during the
+// second build of a PERFECT_HASH table for a key with no position in the
+// hash, which fails the build.
+func (g *Generator) dumpPerfectHashReport(buf *bytes.Buffer, msg *descriptor.DescriptorProto, currentTAB string) {
+	fmt.Fprintf(buf, "%sif(plan.misses > 0)\n", currentTAB)
+	fmt.Fprintf(buf, "%s{\n", currentTAB)
+	fmt.Fprintf(buf, "%s    std::ostringstream report;\n", currentTAB)
+	fmt.Fprintf(buf, "%s    if(!err.empty()) report<<\"; \";\n", currentTAB)
+	fmt.Fprintf(buf, "%s    report<<plan.misses<<\" entries dropped in %s with a key out of the perfect hash\";\n", currentTAB, msg.GetName())
+	fmt.Fprintf(buf, "%s    err += report.str();\n", currentTAB)
+	fmt.Fprintf(buf, "%s    ret = -1;\n", currentTAB)
+	fmt.Fprintf(buf, "%s}\n", currentTAB)
+}
+
+// perfectHashTypesCode holds mmdata::pb::PerfectHashTable, the root table of
+// entries in a single vector found through a minimal perfect hash of their
+// keys, checked against the key of the entry found.
+//
+// The hash is a cascade of bit arrays of twice the size of the keys they get:
+// the keys alone on their bit are set in it, the others go to the next level,
+// and the position of a key is the rank of its bit among all the bits set.
+// It takes a few bits a key and a few hashes of the key for a lookup.
+//
+// The builder gives no hook at the end of the build, and the hash needs all
+// the keys, so the Build helper builds twice: the first build gives the keys
+// to a PerfectHashPlan and inserts nothing, the hash is computed from them,
+// and the second build copies it to the table on the first insert, then
+// inserts the entries. Slots of the positions of the entries in the vector
+// map the positions of the hash, so that the entries dropped by Insert leave
+// no hole in the vector.
+const perfectHashTypesCode = `#ifndef MMDATA_PERFECT_HASH_TYPES_
+#define MMDATA_PERFECT_HASH_TYPES_
+#include <algorithm>
+#include <utility>
+#include <vector>
+namespace mmdata
+{
+    namespace pb
+    {
+        struct PerfectHashPlan
+        {
+            //the hashes of the keys given by the first build, then the hash
+            //of the key at each position
+            std::vector<uint64_t> hashes;
+            //the bit arrays of the levels, the bits set before each word, and
+            //the first word of each level followed by the end
+            std::vector<uint64_t> bits;
+            std::vector<uint32_t> ranks;
+            std::vector<uint32_t> levels;
+            bool prepared;
+            //the entries of the second build with no position in the hash
+            uint64_t misses;
+
+            PerfectHashPlan():prepared(false),misses(0)
+            {}
+
+            static uint64_t Mix(uint64_t h, size_t level)
+            {
+                uint64_t z = h + (level + 1) * 0x9e3779b97f4a7c15ULL;
+                z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9ULL;
+                z = (z ^ (z >> 27)) * 0x94d049bb133111ebULL;
+                return z ^ (z >> 31);
+            }
+            static uint32_t Popcount(uint64_t v)
+            {
+                v = v - ((v >> 1) & 0x5555555555555555ULL);
+                v = (v & 0x3333333333333333ULL) + ((v >> 2) & 0x3333333333333333ULL);
+                v = (v + (v >> 4)) & 0x0f0f0f0f0f0f0f0fULL;
+                return static_cast<uint32_t>((v * 0x0101010101010101ULL) >> 56);
+            }
+            //the position given by the hash to a key hashed to h, any key not
+            //hashed may get one too
+            template<typename Bits, typename Ranks, typename Levels>
+            static bool Lookup(const Bits& bits, const Ranks& ranks, const Levels& levels, uint64_t h, size_t& pos)
+            {
+                for(size_t level = 0; level + 1 < levels.size(); level++)
+                {
+                    uint64_t size = static_cast<uint64_t>(levels[level + 1] - levels[level]) * 64;
+                    uint64_t bit = Mix(h, level) % size;
+                    size_t word = levels[level] + bit / 64;
+                    uint64_t mask = 1ULL << (bit % 64);
+                    if(bits[word] & mask)
+                    {
+                        pos = ranks[word] + Popcount(bits[word] & (mask - 1));
+                        return true;
+                    }
+                }
+                return false;
+            }
+            //computes the hash of the keys given by the first build, the keys
+            //of the same hash taking a single position
+            void Prepare()
+            {
+                std::sort(hashes.begin(), hashes.end());
+                hashes.erase(std::unique(hashes.begin(), hashes.end()), hashes.end());
+                std::vector<uint64_t> keys(hashes);
+                levels.assign(1, 0);
+                //the distinct hashes all end alone on a bit of a level
+                for(size_t level = 0; !keys.empty(); level++)
+                {
+                    size_t words = (keys.size() * 2 + 63) / 64;
+                    std::vector<uint64_t> seen(words), collided(words);
+                    for(size_t i = 0; i < keys.size(); i++)
+                    {
+                        uint64_t bit = Mix(keys[i], level) % (words * 64);
+                        uint64_t mask = 1ULL << (bit % 64);
+                        if(seen[bit / 64] & mask) collided[bit / 64] |= mask;
+                        seen[bit / 64] |= mask;
+                    }
+                    std::vector<uint64_t> next;
+                    for(size_t i = 0; i < keys.size(); i++)
+                    {
+                        uint64_t bit = Mix(keys[i], level) % (words * 64);
+                        if(collided[bit / 64] & (1ULL << (bit % 64))) next.push_back(keys[i]);
+                    }
+                    for(size_t i = 0; i < words; i++)
+                    {
+                        bits.push_back(seen[i] & ~collided[i]);
+                    }
+                    levels.push_back(bits.size());
+                    keys.swap(next);
+                }
+                ranks.resize(bits.size());
+                uint32_t hashed = 0;
+                for(size_t i = 0; i < bits.size(); i++)
+                {
+                    ranks[i] = hashed;
+                    hashed += Popcount(bits[i]);
+                }
+                std::vector<uint64_t> positions(hashes.size());
+                for(size_t i = 0; i < hashes.size(); i++)
+                {
+                    size_t pos = 0;
+                    Lookup(bits, ranks, levels, hashes[i], pos);
+                    positions[pos] = hashes[i];
+                }
+                hashes.swap(positions);
+                prepared = true;
+            }
+        };
+
+        template<typename K, typename V>
+        struct PerfectHashTable
+        {
+            typedef K key_type;
+            typedef V mapped_type;
+            typedef std::pair<K, V> value_type;
+            typedef typename mmdata::SHMVector<value_type>::Type Entries;
+            typedef typename Entries::iterator iterator;
+            typedef typename Entries::const_iterator const_iterator;
+            typedef typename Entries::size_type size_type;
+            typedef typename Entries::allocator_type allocator_type;
+
+            Entries entries_;
+            //the hash copied from the PerfectHashPlan
+            typename mmdata::SHMVector<uint64_t>::Type bits_;
+            typename mmdata::SHMVector<uint32_t>::Type ranks_;
+            typename mmdata::SHMVector<uint32_t>::Type levels_;
+            //the position in entries_ plus one of the entry of each position
+            //of the hash, 0 for none
+            typename mmdata::SHMVector<uint32_t>::Type slots_;
+
+            PerfectHashTable(const mmdata::CharAllocator& alloc):entries_(alloc),bits_(alloc),ranks_(alloc),levels_(alloc),slots_(alloc)
+            {}
+
+            iterator begin() { return entries_.begin(); }
+            iterator end() { return entries_.end(); }
+            const_iterator begin() const { return entries_.begin(); }
+            const_iterator end() const { return entries_.end(); }
+            size_type size() const { return entries_.size(); }
+            bool empty() const { return entries_.empty(); }
+            allocator_type get_allocator() const { return entries_.get_allocator(); }
+
+            iterator find(const K& key) { return begin() + Position(key); }
+            const_iterator find(const K& key) const { return begin() + Position(key); }
+            size_type count(const K& key) const { return find(key) == end() ? 0 : 1; }
+
+            //whether the entry of a key is inserted by the build running: the
+            //first build of the Build helper gives the key to the plan, the
+            //second one needs the key to have its position in the hash
+            bool Hashed(const K& key)
+            {
+                BuildContext* ctx = BuildContext::Current();
+                if(NULL == ctx || NULL == ctx->perfect_hash) return false;
+                PerfectHashPlan& plan = *ctx->perfect_hash;
+                uint64_t h = boost::hash<K>()(key);
+                if(!plan.prepared)
+                {
+                    plan.hashes.push_back(h);
+                    return false;
+                }
+                if(levels_.empty())
+                {
+                    bits_.assign(plan.bits.begin(), plan.bits.end());
+                    ranks_.assign(plan.ranks.begin(), plan.ranks.end());
+                    levels_.assign(plan.levels.begin(), plan.levels.end());
+                    slots_.resize(plan.hashes.size());
+                }
+                size_t slot;
+                //a key of the first build, and no other key of the same hash
+                if(PerfectHashPlan::Lookup(bits_, ranks_, levels_, h, slot) && plan.hashes[slot] == h &&
+                    (0 == slots_[slot] || entries_[slots_[slot] - 1].first == key))
+                {
+                    return true;
+                }
+                plan.misses++;
+                return false;
+            }
+            //inserts the entry of a key Hashed()
+            std::pair<iterator, bool> insert(const value_type& entry)
+            {
+                size_t slot = 0;
+                PerfectHashPlan::Lookup(bits_, ranks_, levels_, boost::hash<K>()(entry.first), slot);
+                if(0 != slots_[slot]) return std::make_pair(begin() + (slots_[slot] - 1), false);
+                entries_.push_back(entry);
+                slots_[slot] = static_cast<uint32_t>(entries_.size());
+                return std::make_pair(end() - 1, true);
+            }
+
+        private:
+            //the position of the entry of a key, or the size if none
+            size_t Position(const K& key) const
+            {
+                size_t slot;
+                if(!PerfectHashPlan::Lookup(bits_, ranks_, levels_, boost::hash<K>()(key), slot) || 0 == slots_[slot])
+                {
+                    return entries_.size();
+                }
+                size_t pos = slots_[slot] - 1;
+                return entries_[pos].first == key ? pos : entries_.size();
+            }
+        };
+    }
+}
+#endif /* MMDATA_PERFECT_HASH_TYPES_ */
+`
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// hasPresence returns whether a field tracks if it is set, which is the case
+// of proto3 optional fields, wrapper type fields and proto2 optional fields out
+// of oneofs.
+func (g *Generator) hasPresence(field *descriptor.FieldDescriptorProto) bool {
+	if field.GetProto3Optional() {
+		return true
+	}
+	if field.GetLabel() != descriptor.FieldDescriptorProto_LABEL_OPTIONAL || isOneofField(field) {
+		return false
+	}
+	if nil != g.wrappedField(field) {
+		return true
+	}
+	t, _ := g.registry.FieldOwner(field)
+	if nil == t {
+		return false
+	}
+	syntax := t.File.GetSyntax()
+	return syntax == "" || syntax == "proto2"
+}
+
+// hasBitsLayout is hashed by GetHash() after a message with has-bits: the
+// descriptor of a proto2 message is the same as the one of the proto3
+// message without optional, while the has-bits change the layout.
+var hasBitsLayout = []byte("mmdata.has_bits:1")
+
+// presenceFields returns the fields of a message tracking presence, the index
+// of a field in it is its bit in the has-bits words.
+func (g *Generator) presenceFields(msg *descriptor.DescriptorProto) []*descriptor.FieldDescriptorProto {
+	var fields []*descriptor.FieldDescriptorProto
+	for _, field := range msg.Field {
+		if g.hasPresence(field) {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// hasBit returns the has-bits word and mask of the i-th presence field.
+func hasBit(i int) (string, string) {
+	return fmt.Sprintf("has_bits_[%d]", i/32), fmt.Sprintf("0x%xu", uint32(1)<<uint(i%32))
+}
+
+func (g *Generator) dumpHasBits(buf *bytes.Buffer, msg *descriptor.DescriptorProto, currentTAB string) {
+	fields := g.presenceFields(msg)
+	if len(fields) == 0 {
+		return
+	}
+	fmt.Fprintf(buf, "%suint32_t has_bits_[%d];\n", currentTAB, (len(fields)+31)/32)
+}
+
+// dumpPresenceAccessors emits has_x()/set_x()/clear_x() of the fields tracking
+// presence, clear_x() resets strings and scalars too.
+func (g *Generator) dumpPresenceAccessors(buf *bytes.Buffer, msg *descriptor.DescriptorProto, currentTAB string) {
+	fields := g.presenceFields(msg)
+	if len(fields) > 0 {
+		fmt.Fprintf(buf, "\n")
+	}
+	for i, field := range fields {
+		name := field.GetName()
+		word, mask := hasBit(i)
+		fmt.Fprintf(buf, "%sbool has_%s() const { return (%s & %s) != 0; }\n", currentTAB, name, word, mask)
+		fmt.Fprintf(buf, "%svoid set_has_%s() { %s |= %s; }\n", currentTAB, name, word, mask)
+		fmt.Fprintf(buf, "%svoid set_%s(const %s& v) { this->%s = v; set_has_%s(); }\n", currentTAB, name, g.getFieldType(field), name, name)
+		fmt.Fprintf(buf, "%svoid clear_%s()\n", currentTAB, name)
+		fmt.Fprintf(buf, "%s{\n", currentTAB)
+		fmt.Fprintf(buf, "%s    %s &= ~%s;\n", currentTAB, word, mask)
+		if g.isCustomType(field) {
+			//a custom type keeps its value, only the bit is cleared
+			fmt.Fprintf(buf, "%s}\n", currentTAB)
+			continue
+		}
+		switch g.valueField(field).GetType() {
+		case descriptor.FieldDescriptorProto_TYPE_STRING, descriptor.FieldDescriptorProto_TYPE_BYTES:
+			if v, exist := g.withDefaultValue(field); exist {
+				fmt.Fprintf(buf, "%s    %s.assign(%s);\n", currentTAB, name, v)
+			} else {
+				fmt.Fprintf(buf, "%s    %s.clear();\n", currentTAB, name)
+			}
+		case descriptor.FieldDescriptorProto_TYPE_MESSAGE:
+			if isWellKnownStruct(field) {
+				fmt.Fprintf(buf, "%s    %s = %s();\n", currentTAB, name, g.getFieldType(field))
+				break
+			}
+			//a message keeps its content, only the bit is cleared
+		default:
+			if v, exist := g.withDefaultValue(field); exist {
+				fmt.Fprintf(buf, "%s    %s = %s;\n", currentTAB, name, v)
+			} else {
+				fmt.Fprintf(buf, "%s    %s = %s();\n", currentTAB, name, g.getFieldType(field))
+			}
+		}
+		fmt.Fprintf(buf, "%s}\n", currentTAB)
+	}
+}
+
+// presenceMessages returns the messages with fields tracking presence, in
+// nested struct mode including the nested ones.
+func (g *Generator) presenceMessages(msg *descriptor.DescriptorProto) []*descriptor.DescriptorProto {
+	var msgs []*descriptor.DescriptorProto
+	if g.config.Nested != nestedMangle {
+		sorted, _ := g.sortNestedTypes(msg)
+		for _, nest := range sorted {
+			msgs = append(msgs, g.presenceMessages(nest)...)
+		}
+	}
+	if len(g.presenceFields(msg)) > 0 {
+		msgs = append(msgs, msg)
+	}
+	return msgs
+}
+
+// dumpPresenceKcfg emits the kcfg json mapping of the messages of a top-level
+// message with fields tracking presence, which skips the fields not set and
+// sets the parsed ones. It is out of the namespaces of the file, so they are
+// closed before and opened again after.
+func (g *Generator) dumpPresenceKcfg(msg *descriptor.DescriptorProto) {
+	msgs := g.presenceMessages(msg)
+	if len(msgs) == 0 {
+		return
+	}
+	buf := &g.OutputBuffer
+	g.dumpNamespaceClose(buf, namespaceTabs(g.config.Namespaces(g.packageName)))
+	fmt.Fprintf(buf, "namespace kcfg\n{\n")
+	for _, m := range msgs {
+		g.dumpMessageKcfg(buf, m, "    ")
+	}
+	fmt.Fprintf(buf, "}\n")
+	g.dumpNamespaceOpen(buf, g.packageName)
+}
+
+func (g *Generator) dumpMessageKcfg(buf *bytes.Buffer, msg *descriptor.DescriptorProto, currentTAB string) {
+	name := g.cppNamespace(g.packageName) + "::" + g.messageName(msg)
+	funcTab := currentTAB + "    "
+	blockTab := funcTab + "    "
+	fmt.Fprintf(buf, "%sinline bool Parse(const rapidjson::Value& json, const char* name, %s& v)\n", currentTAB, name)
+	fmt.Fprintf(buf, "%s{\n", currentTAB)
+	fmt.Fprintf(buf, "%sconst rapidjson::Value* val = &json;\n", funcTab)
+	fmt.Fprintf(buf, "%sif(NULL != name && name[0] != 0)\n", funcTab)
+	fmt.Fprintf(buf, "%s{\n", funcTab)
+	fmt.Fprintf(buf, "%sif(!json.IsObject() || !json.HasMember(name)) return false;\n", blockTab)
+	fmt.Fprintf(buf, "%sval = &json[name];\n", blockTab)
+	fmt.Fprintf(buf, "%s}\n", funcTab)
+	fmt.Fprintf(buf, "%sif(!val->IsObject()) return false;\n", funcTab)
+	for _, m := range structMembers(msg) {
+		if m.oneof < 0 && g.hasPresence(m.field) {
+			fmt.Fprintf(buf, "%sif(Parse(*val, \"%s\", v.%s)) v.set_has_%s();\n", funcTab, m.name, m.name, m.name)
+		} else {
+			fmt.Fprintf(buf, "%sParse(*val, \"%s\", v.%s);\n", funcTab, m.name, m.name)
+		}
+	}
+	fmt.Fprintf(buf, "%sreturn true;\n", funcTab)
+	fmt.Fprintf(buf, "%s}\n", currentTAB)
+
+	fmt.Fprintf(buf, "%sinline void Serialize(rapidjson::Value& json, rapidjson::Value::AllocatorType& allocator, const char* name, const %s& v)\n", currentTAB, name)
+	fmt.Fprintf(buf, "%s{\n", currentTAB)
+	fmt.Fprintf(buf, "%srapidjson::Value val(rapidjson::kObjectType);\n", funcTab)
+	for _, m := range structMembers(msg) {
+		if m.oneof < 0 && g.hasPresence(m.field) {
+			fmt.Fprintf(buf, "%sif(v.has_%s()) Serialize(val, allocator, \"%s\", v.%s);\n", funcTab, m.name, m.name, m.name)
+		} else {
+			fmt.Fprintf(buf, "%sSerialize(val, allocator, \"%s\", v.%s);\n", funcTab, m.name, m.name)
+		}
+	}
+	fmt.Fprintf(buf, "%sif(NULL != name && name[0] != 0)\n", funcTab)
+	fmt.Fprintf(buf, "%s{\n", funcTab)
+	fmt.Fprintf(buf, "%sjson.AddMember(rapidjson::Value(name, allocator).Move(), val, allocator);\n", blockTab)
+	fmt.Fprintf(buf, "%s}\n", funcTab)
+	fmt.Fprintf(buf, "%selse\n", funcTab)
+	fmt.Fprintf(buf, "%s{\n", funcTab)
+	fmt.Fprintf(buf, "%sjson = val;\n", blockTab)
+	fmt.Fprintf(buf, "%s}\n", funcTab)
+	fmt.Fprintf(buf, "%s}\n", currentTAB)
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	plugin "github.com/golang/protobuf/protoc-gen-go/plugin"
+)
+
+//go:generate protoc -Itestdata -I. --include_imports --include_source_info --descriptor_set_out=testdata/presence.pb testdata/presence.proto
+
+func TestPresence(t *testing.T) {
+	response := process(testRequest(t, "presence", ""))
+	if response.GetSupportedFeatures()&uint64(plugin.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL) == 0 {
+		t.Errorf("SupportedFeatures = %d, want FEATURE_PROTO3_OPTIONAL", response.GetSupportedFeatures())
+	}
+	header := testGenerate(t, "presence", "")["presence.proto.hpp"]
+	for _, want := range []string{
+		"            OOneof o;\n            uint32_t has_bits_[1];\n",
+		"Item(const mmdata::CharAllocator& alloc):id(0),name(alloc),n(0),o(alloc),has_bits_()",
+		"bool has_name() const { return (has_bits_[0] & 0x1u) != 0; }",
+		"void set_n(const int32_t& v) { this->n = v; set_has_n(); }",
+		"has_bits_[0] &= ~0x2u;\n                n = 0;\n",
+		"if(v.has_name()) os<< v.name;\n            else os<<\"null\";\n",
+	} {
+		if !strings.Contains(header, want) {
+			t.Errorf("presence.proto.hpp does not contain %q:\n%s", want, header)
+		}
+	}
+	//fields of oneofs and proto3 fields without optional have no has-bit
+	for _, forbidden := range []string{"has_id()", "set_has_a()"} {
+		if strings.Contains(header, forbidden) {
+			t.Errorf("presence.proto.hpp contains %q:\n%s", forbidden, header)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// MessageType is a message declared in one of the files of the request.
+type MessageType struct {
+	File *descriptor.FileDescriptorProto
+	Desc *descriptor.DescriptorProto
+	//fully-qualified proto name, like ".pkg.Outer.Inner"
+	FullName string
+	//SourceCodeInfo path of the message
+	Path []int32
+}
+
+// Package returns the proto package of the file declaring the message.
+func (t *MessageType) Package() string {
+	return t.File.GetPackage()
+}
+
+// LocalName returns the name relative to the package, like "Outer.Inner".
+func (t *MessageType) LocalName() string {
+	return localName(t.Package(), t.FullName)
+}
+
+// EnumType is an enum declared in one of the files of the request.
+type EnumType struct {
+	File *descriptor.FileDescriptorProto
+	Desc *descriptor.EnumDescriptorProto
+	//fully-qualified proto name, like ".pkg.Outer.Color"
+	FullName string
+	//SourceCodeInfo path of the enum
+	Path []int32
+}
+
+func (t *EnumType) Package() string {
+	return t.File.GetPackage()
+}
+
+// LocalName returns the name relative to the package, like "Outer.Color".
+func (t *EnumType) LocalName() string {
+	return localName(t.Package(), t.FullName)
+}
+
+func localName(pkg string, fullName string) string {
+	if len(pkg) == 0 {
+		return fullName[1:]
+	}
+	return fullName[len(pkg)+2:]
+}
+
+// Registry indexes the types of all files of a CodeGeneratorRequest, so that
+// a type imported from another file resolves like a local one.
+type Registry struct {
+	files    map[string]*descriptor.FileDescriptorProto
+	messages map[string]*MessageType
+	enums    map[string]*EnumType
+	//enums of all files, top-level ones of a file before the nested ones
+	enumList []*EnumType
+	descs    map[*descriptor.DescriptorProto]*MessageType
+	fields   map[*descriptor.FieldDescriptorProto]fieldRef
+}
+
+type fieldRef struct {
+	owner *MessageType
+	index int
+}
+
+func NewRegistry(files []*descriptor.FileDescriptorProto) *Registry {
+	r := &Registry{
+		files:    make(map[string]*descriptor.FileDescriptorProto),
+		messages: make(map[string]*MessageType),
+		enums:    make(map[string]*EnumType),
+		descs:    make(map[*descriptor.DescriptorProto]*MessageType),
+		fields:   make(map[*descriptor.FieldDescriptorProto]fieldRef),
+	}
+	for _, file := range files {
+		r.files[file.GetName()] = file
+		prefix := ""
+		if len(file.GetPackage()) > 0 {
+			prefix = "." + file.GetPackage()
+		}
+		for i, enum := range file.EnumType {
+			r.addEnum(file, prefix, enum, []int32{fileEnumTypePath, int32(i)})
+		}
+		for i, msg := range file.MessageType {
+			r.addMessage(file, prefix, msg, []int32{fileMessageTypePath, int32(i)})
+		}
+	}
+	return r
+}
+
+func (r *Registry) addMessage(file *descriptor.FileDescriptorProto, prefix string, msg *descriptor.DescriptorProto, path []int32) {
+	name := prefix + "." + msg.GetName()
+	t := &MessageType{File: file, Desc: msg, FullName: name, Path: path}
+	r.messages[name] = t
+	r.descs[msg] = t
+	for i, field := range msg.Field {
+		r.fields[field] = fieldRef{owner: t, index: i}
+	}
+	for i, enum := range msg.EnumType {
+		enumPath := append(append([]int32{}, path...), messageEnumPath, int32(i))
+		r.addEnum(file, name, enum, enumPath)
+	}
+	for i, nest := range msg.NestedType {
+		nestPath := append(append([]int32{}, path...), messageNestedPath, int32(i))
+		r.addMessage(file, name, nest, nestPath)
+	}
+}
+
+// File returns the file with the name, or nil.
+func (r *Registry) File(name string) *descriptor.FileDescriptorProto {
+	f, exist := r.files[name]
+	if exist {
+		return f
+	}
+	return nil
+}
+
+func (r *Registry) addEnum(file *descriptor.FileDescriptorProto, prefix string, enum *descriptor.EnumDescriptorProto, path []int32) {
+	name := prefix + "." + enum.GetName()
+	t := &EnumType{File: file, Desc: enum, FullName: name, Path: path}
+	r.enums[name] = t
+	r.enumList = append(r.enumList, t)
+}
+
+// Enum returns the enum with the fully-qualified name, or nil.
+func (r *Registry) Enum(name string) *EnumType {
+	t, exist := r.enums[name]
+	if exist {
+		return t
+	}
+	return nil
+}
+
+// FileEnums returns all enums declared in the file, nested ones included.
+func (r *Registry) FileEnums(file *descriptor.FileDescriptorProto) []*EnumType {
+	var enums []*EnumType
+	for _, t := range r.enumList {
+		if t.File == file {
+			enums = append(enums, t)
+		}
+	}
+	return enums
+}
+
+// Message returns the message with the fully-qualified name, or nil.
+func (r *Registry) Message(name string) *MessageType {
+	t, exist := r.messages[name]
+	if exist {
+		return t
+	}
+	return nil
+}
+
+// MessageOf returns the registered type of a message descriptor, or nil.
+func (r *Registry) MessageOf(desc *descriptor.DescriptorProto) *MessageType {
+	t, exist := r.descs[desc]
+	if exist {
+		return t
+	}
+	return nil
+}
+
+// FieldOwner returns the message declaring the field and the field index.
+func (r *Registry) FieldOwner(field *descriptor.FieldDescriptorProto) (*MessageType, int) {
+	ref, exist := r.fields[field]
+	if exist {
+		return ref.owner, ref.index
+	}
+	return nil, -1
+}
@@ -0,0 +1,45 @@
+#ifndef MMDATA_TEST_BUILDER_HPP_
+#define MMDATA_TEST_BUILDER_HPP_
+#include <functional>
+#include <memory>
+#include <string>
+#include <vector>
+#include "mmdata.hpp"
+
+//TestBuilder stands for mmdata::DataImageBuilder in BuildWith of the generated
+//helpers: Build<T> creates the root table of T and inserts the entries given
+//to Add in order, like the image builder does with the entries of its source,
+//with the default allocator instead of the memory of an image.
+template<typename T>
+struct TestBuilder
+{
+    typedef typename T::table_type Table;
+    std::string err;
+    mmdata::CharAllocator alloc;
+    std::vector<std::function<void(T&)> > entries;
+    std::unique_ptr<Table> table;
+    //the builds run, twice for a PERFECT_HASH table
+    int builds;
+
+    TestBuilder():builds(0)
+    {}
+    void Add(const std::function<void(T&)>& fill)
+    {
+        entries.push_back(fill);
+    }
+    template<typename U>
+    int64_t Build(mmdata::DataImageBuildOptions&)
+    {
+        builds++;
+        table.reset(new Table(alloc));
+        for(size_t i = 0; i < entries.size(); i++)
+        {
+            U entry(alloc);
+            entries[i](entry);
+            table->Insert(entry);
+        }
+        return static_cast<int64_t>(table->size());
+    }
+};
+
+#endif /* MMDATA_TEST_BUILDER_HPP_ */
@@ -0,0 +1,18 @@
+#ifndef MMDATA_TEST_CHECK_HPP_
+#define MMDATA_TEST_CHECK_HPP_
+#include <cstdlib>
+#include <iostream>
+
+//CHECK exits the test program reporting the condition if it is false, the
+//tests print "ok" once all their checks passed
+#define CHECK(cond) \
+    do \
+    { \
+        if(!(cond)) \
+        { \
+            std::cerr << __FILE__ << ":" << __LINE__ << ": CHECK(" #cond ") failed" << std::endl; \
+            std::exit(1); \
+        } \
+    } while(0)
+
+#endif /* MMDATA_TEST_CHECK_HPP_ */
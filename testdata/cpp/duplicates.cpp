@@ -0,0 +1,63 @@
+#include "check.hpp"
+#include "builder.hpp"
+#include "duplicates.proto.cpp"
+
+using namespace test::duplicates;
+
+int main()
+{
+    mmdata::DataImageBuildOptions options;
+    std::string err;
+
+    TestBuilder<First> first;
+    first.Add([](First& entry) { entry.k = 1; entry.v.assign("a"); });
+    first.Add([](First& entry) { entry.k = 1; entry.v.assign("b"); });
+    first.Add([](First& entry) { entry.k = 2; entry.v.assign("c"); });
+    CHECK(FirstTableHelper::BuildWith(first, options, err) == 2);
+    CHECK(err == "1 duplicate keys in First, kept the first entries (mmdata.duplicate_key = KEEP_FIRST)");
+    CHECK(first.table->find(1)->second == "a");
+
+    //the counters are the ones of each build
+    TestBuilder<First> again;
+    again.Add([](First& entry) { entry.k = 1; entry.v.assign("a"); });
+    CHECK(FirstTableHelper::BuildWith(again, options, err) == 1);
+    CHECK(err.empty());
+
+    TestBuilder<Last> last;
+    last.Add([](Last& entry) { entry.k = 1; entry.v.a = 1; });
+    last.Add([](Last& entry) { entry.k = 1; entry.v.a = 2; });
+    last.Add([](Last& entry) { entry.k = 1; entry.v.a = 3; });
+    CHECK(LastTableHelper::BuildWith(last, options, err) == 1);
+    CHECK(err == "2 duplicate keys in Last, kept the last entries (mmdata.duplicate_key = KEEP_LAST)");
+    CHECK(last.table->find(1)->second.a == 3);
+
+    TestBuilder<Merge> merge;
+    merge.Add([](Merge& entry) { entry.k = 1; Item item(entry.v.get_allocator()); item.a = 1; entry.v.push_back(item); });
+    merge.Add([](Merge& entry) { entry.k = 1; Item item(entry.v.get_allocator()); item.a = 2; entry.v.push_back(item); item.a = 3; entry.v.push_back(item); });
+    CHECK(MergeTableHelper::BuildWith(merge, options, err) == 1);
+    CHECK(err == "1 duplicate keys in Merge, merged the values (mmdata.duplicate_key = MERGE)");
+    const Merge::value_type& merged = merge.table->find(1)->second;
+    CHECK(merged.size() == 3);
+    CHECK(merged[0].a == 1 && merged[1].a == 2 && merged[2].a == 3);
+
+    TestBuilder<Fail> fail;
+    fail.Add([](Fail& entry) { entry.k = 1; entry.code.assign("x"); });
+    fail.Add([](Fail& entry) { entry.k = 1; entry.code.assign("y"); });
+    CHECK(FailTableHelper::BuildWith(fail, options, err) == -1);
+    CHECK(err == "1 duplicate keys in Fail, failed the build (mmdata.duplicate_key = FAIL_BUILD)");
+
+    TestBuilder<Fail> conflict;
+    conflict.Add([](Fail& entry) { entry.k = 1; entry.code.assign("x"); });
+    conflict.Add([](Fail& entry) { entry.k = 2; entry.code.assign("x"); });
+    CHECK(FailTableHelper::BuildWith(conflict, options, err) == -1);
+    CHECK(err == "1 entries dropped in Fail for a value of a (mmdata.unique_index) already inserted");
+
+    TestBuilder<Fail> valid;
+    valid.Add([](Fail& entry) { entry.k = 1; entry.code.assign("x"); });
+    valid.Add([](Fail& entry) { entry.k = 2; entry.code.assign("y"); });
+    CHECK(FailTableHelper::BuildWith(valid, options, err) == 2);
+    CHECK(err.empty());
+
+    std::cout << "ok" << std::endl;
+    return 0;
+}
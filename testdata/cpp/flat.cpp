@@ -0,0 +1,101 @@
+#include "check.hpp"
+#include "builder.hpp"
+#include "flat.proto.cpp"
+
+using namespace test::flat;
+
+//checks the entries are iterated in key order and all found
+template<typename Table>
+static bool Sorted(const Table& table)
+{
+    for(typename Table::const_iterator it = table.begin(); it != table.end(); ++it)
+    {
+        if(it != table.begin() && !((it - 1)->first < it->first)) return false;
+        if(table.find(it->first) != it) return false;
+    }
+    return true;
+}
+
+int main()
+{
+    mmdata::DataImageBuildOptions options;
+    std::string err;
+
+    //keys out of order, with a duplicate of each tenth key
+    TestBuilder<Plain> plain;
+    for(int64_t i = 0; i < 1000; i++)
+    {
+        int64_t k = i * 7919 % 1000;
+        plain.Add([=](Plain& entry) { entry.k = 2 * k; entry.v.assign("first"); });
+        if(i % 10 == 0) plain.Add([=](Plain& entry) { entry.k = 2 * k; entry.v.assign("last"); });
+    }
+    CHECK(PlainTableHelper::BuildWith(plain, options, err) == 1000);
+    CHECK(err == "100 duplicate keys in Plain, kept the first entries (mmdata.duplicate_key = KEEP_FIRST)");
+    const PlainTable& plains = *plain.table;
+    CHECK(Sorted(plains));
+    CHECK(plains.begin()->first == 0);
+    CHECK(plains.find(1998)->second == "first");
+    CHECK(plains.find(1) == plains.end());
+    CHECK(plains.find(-1) == plains.end());
+    CHECK(plains.find(2000) == plains.end());
+    CHECK(plains.count(500) == 1 && plains.count(501) == 0);
+
+    //keys in order, appended after the layout
+    TestBuilder<Eyt> sorted;
+    for(int64_t k = 0; k < 1000; k++)
+    {
+        sorted.Add([=](Eyt& entry) { entry.k = 2 * k; entry.v.a = static_cast<int32_t>(k); entry.v.s.assign(k % 2 ? "odd" : "even"); });
+    }
+    CHECK(EytTableHelper::BuildWith(sorted, options, err) == 1000);
+    CHECK(err.empty());
+    const EytTable& eyt = *sorted.table;
+    CHECK(Sorted(eyt));
+    CHECK(8 * eyt.layout_.size() >= 7 * eyt.size());
+    for(int64_t k = -1; k <= 2000; k++)
+    {
+        EytTable::const_iterator found = eyt.find(k);
+        if(k >= 0 && k < 2000 && k % 2 == 0)
+        {
+            CHECK(found != eyt.end() && found->second.a == k / 2);
+        }
+        else
+        {
+            CHECK(found == eyt.end());
+        }
+    }
+    CHECK(eyt.FindByS("odd").size() == 500);
+
+    //keys out of order, inserted among the entries of the layout
+    TestBuilder<Eyt> shuffled;
+    for(int64_t i = 0; i < 1000; i++)
+    {
+        int64_t k = i * 7919 % 1000;
+        shuffled.Add([=](Eyt& entry) { entry.k = 2 * k; entry.v.a = static_cast<int32_t>(k); });
+    }
+    CHECK(EytTableHelper::BuildWith(shuffled, options, err) == 1000);
+    CHECK(Sorted(*shuffled.table));
+    CHECK(shuffled.table->find(1000)->second.a == 500);
+    CHECK(shuffled.table->find(999) == shuffled.table->end());
+
+    TestBuilder<Comp> comp;
+    comp.Add([](Comp& entry) { entry.a.assign("b"); entry.b = 1; entry.v = 1; });
+    comp.Add([](Comp& entry) { entry.a.assign("a"); entry.b = 2; entry.v = 2; });
+    comp.Add([](Comp& entry) { entry.a.assign("b"); entry.b = 1; entry.v = 3; });
+    CHECK(CompTableHelper::BuildWith(comp, options, err) == 2);
+    CHECK(err == "1 duplicate keys in Comp, kept the last entries (mmdata.duplicate_key = KEEP_LAST)");
+    CHECK(comp.table->begin()->first.a == "a");
+    CHECK(comp.table->find(Comp::key_type(mmdata::SHMString("b"), 1))->second == 3);
+    CHECK(comp.table->find(Comp::key_type(mmdata::SHMString("b"), 2)) == comp.table->end());
+
+    TestBuilder<Merged> merged;
+    merged.Add([](Merged& entry) { entry.k = 2; entry.v.push_back(1); });
+    merged.Add([](Merged& entry) { entry.k = 1; entry.v.push_back(2); });
+    merged.Add([](Merged& entry) { entry.k = 2; entry.v.push_back(3); entry.v.push_back(4); });
+    CHECK(MergedTableHelper::BuildWith(merged, options, err) == 2);
+    CHECK(err == "1 duplicate keys in Merged, merged the values (mmdata.duplicate_key = MERGE)");
+    const Merged::value_type& values = merged.table->find(2)->second;
+    CHECK(values.size() == 3 && values[0] == 1 && values[1] == 3 && values[2] == 4);
+
+    std::cout << "ok" << std::endl;
+    return 0;
+}
@@ -0,0 +1,69 @@
+#include "check.hpp"
+#include "builder.hpp"
+#include "indexes.proto.cpp"
+
+using namespace test::indexes;
+
+static void AddItem(TestBuilder<ItemData>& builder, int64_t id, const char* title, int32_t category, int32_t shop)
+{
+    builder.Add([=](ItemData& entry)
+    {
+        entry.set_id(id);
+        if(NULL != title)
+        {
+            entry.item.title.assign(title);
+            entry.item.set_has_title();
+        }
+        if(category > 0) entry.item.set_category(category);
+        entry.item.shop = shop;
+    });
+}
+
+int main()
+{
+    mmdata::DataImageBuildOptions options;
+    std::string err;
+
+    TestBuilder<ItemData> items;
+    AddItem(items, 1, "x", 5, 9);
+    AddItem(items, 2, "x", 6, 9);
+    AddItem(items, 3, "y", 5, 9);
+    AddItem(items, 4, NULL, 0, 9);
+    CHECK(ItemDataTableHelper::BuildWith(items, options, err) == 3);
+    CHECK(err == "1 entries dropped in ItemData for a value of a (mmdata.unique_index) already inserted");
+    const ItemDataTable& table = *items.table;
+    CHECK(table.find(2) == table.end());
+    CHECK(table.FindByTitle("x")->first == 1);
+    CHECK(table.FindByTitle(std::string("y"))->first == 3);
+    CHECK(table.FindByTitle("z") == table.end());
+    CHECK(table.FindByCategory(5).size() == 2);
+    CHECK(table.FindByCategory(6).empty());
+    CHECK(table.FindByShopId(9).size() == 3);
+
+    //the counters are the ones of each build
+    TestBuilder<ItemData> again;
+    AddItem(again, 1, "x", 5, 9);
+    CHECK(ItemDataTableHelper::BuildWith(again, options, err) == 1);
+    CHECK(err.empty());
+
+    TestBuilder<Stat> stats;
+    stats.Add([](Stat& entry) { entry.set_id(1); entry.set_clicks(4); });
+    stats.Add([](Stat& entry) { entry.set_id(2); entry.set_clicks(4); });
+    stats.Add([](Stat& entry) { entry.set_id(3); });
+    CHECK(StatTableHelper::BuildWith(stats, options, err) == 3);
+    CHECK(stats.table->FindByClicks(4).size() == 2);
+    CHECK(stats.table->FindByClicks(0).empty());
+
+    TestBuilder<Tagged> tagged;
+    tagged.Add([](Tagged& entry) { entry.set_id(1); entry.tag.assign("t"); entry.set_has_tag(); entry.code.assign("c1"); entry.set_has_code(); });
+    tagged.Add([](Tagged& entry) { entry.set_id(2); entry.tag.assign("t"); entry.set_has_tag(); entry.code.assign("c1"); entry.set_has_code(); });
+    tagged.Add([](Tagged& entry) { entry.set_id(3); entry.code.assign("c1"); entry.set_has_code(); });
+    tagged.Add([](Tagged& entry) { entry.set_id(4); entry.code.assign("c2"); entry.set_has_code(); });
+    CHECK(TaggedTableHelper::BuildWith(tagged, options, err) == 2);
+    CHECK(err == "2 entries dropped in Tagged for a value of a (mmdata.unique_index) already inserted");
+    CHECK(tagged.table->FindByTag("t").size() == 1);
+    CHECK(tagged.table->FindByCode("c2")->first == 4);
+
+    std::cout << "ok" << std::endl;
+    return 0;
+}
@@ -0,0 +1,59 @@
+#include <algorithm>
+#include "check.hpp"
+#include "builder.hpp"
+#include "multi.proto.cpp"
+
+using namespace test::multi;
+
+static void AddClick(TestBuilder<Click>& builder, int64_t user, const char* item)
+{
+    builder.Add([=](Click& entry) { entry.user = user; entry.item.assign(item); });
+}
+
+static void AddOrdered(TestBuilder<Ordered>& builder, int64_t user, const char* item, int32_t n)
+{
+    builder.Add([=](Ordered& entry) { entry.user = user; entry.item.assign(item); entry.n = n; });
+}
+
+int main()
+{
+    mmdata::DataImageBuildOptions options;
+    std::string err;
+
+    TestBuilder<Click> clicks;
+    AddClick(clicks, 1, "a");
+    AddClick(clicks, 2, "c");
+    AddClick(clicks, 1, "b");
+    AddClick(clicks, 1, "a");
+    CHECK(ClickTableHelper::BuildWith(clicks, options, err) == 4);
+    CHECK(err.empty());
+    std::vector<std::string> items;
+    std::pair<ClickTable::const_iterator, ClickTable::const_iterator> range = clicks.table->EqualRange(1);
+    for(ClickTable::const_iterator it = range.first; it != range.second; ++it)
+    {
+        items.push_back(std::string(it->second.data(), it->second.size()));
+    }
+    std::sort(items.begin(), items.end());
+    CHECK(items.size() == 3 && items[0] == "a" && items[1] == "a" && items[2] == "b");
+    range = clicks.table->EqualRange(2);
+    CHECK(std::distance(range.first, range.second) == 1);
+    range = clicks.table->EqualRange(3);
+    CHECK(range.first == range.second);
+
+    //a tree multimap keeps the entries of a key in their order of insertion
+    TestBuilder<Ordered> ordered;
+    AddOrdered(ordered, 1, "x", 1);
+    AddOrdered(ordered, 1, "y", 2);
+    AddOrdered(ordered, 1, "x", 3);
+    CHECK(OrderedTableHelper::BuildWith(ordered, options, err) == 3);
+    Ordered::key_type key(ordered.alloc);
+    key.user = 1;
+    key.item.assign("x");
+    std::pair<OrderedTable::const_iterator, OrderedTable::const_iterator> ordered_range = ordered.table->EqualRange(key);
+    CHECK(std::distance(ordered_range.first, ordered_range.second) == 2);
+    CHECK(ordered_range.first->second == 1);
+    CHECK((++ordered_range.first)->second == 3);
+
+    std::cout << "ok" << std::endl;
+    return 0;
+}
@@ -0,0 +1,73 @@
+#include "check.hpp"
+#include "builder.hpp"
+#include "perfect_hash.proto.cpp"
+
+using namespace test::perfect_hash;
+
+int main()
+{
+    mmdata::DataImageBuildOptions options;
+    std::string err;
+
+    TestBuilder<Num> num;
+    for(int64_t i = 0; i < 5000; i++)
+    {
+        int64_t k = i * 7919 % 5000;
+        num.Add([=](Num& entry) { entry.k = 3 * k; entry.v.a = static_cast<int32_t>(k); entry.v.code.assign(std::to_string(k).c_str()); });
+    }
+    num.Add([](Num& entry) { entry.k = 0; entry.v.a = -1; });
+    num.Add([](Num& entry) { entry.k = 1; entry.v.code.assign("7"); });
+    CHECK(NumTableHelper::BuildWith(num, options, err) == 5000);
+    CHECK(num.builds == 2);
+    CHECK(err == "1 entries dropped in Num for a value of a (mmdata.unique_index) already inserted; 1 duplicate keys in Num, kept the first entries (mmdata.duplicate_key = KEEP_FIRST)");
+    const NumTable& nums = *num.table;
+    //one slot a key, with a hole for the key dropped by the unique index
+    CHECK(nums.slots_.size() == 5001);
+    for(int64_t k = -1; k < 15000; k++)
+    {
+        NumTable::const_iterator found = nums.find(k);
+        if(k >= 0 && k % 3 == 0)
+        {
+            CHECK(found != nums.end() && found->first == k && found->second.a == k / 3);
+        }
+        else
+        {
+            CHECK(found == nums.end());
+        }
+    }
+    CHECK(nums.count(15000) == 0);
+    CHECK(nums.FindByCode("7")->first == 21);
+
+    TestBuilder<Str> str;
+    str.Add([](Str& entry) { entry.k.assign("b"); entry.v.push_back(1); });
+    str.Add([](Str& entry) { entry.k.assign("a"); entry.v.push_back(2); });
+    str.Add([](Str& entry) { entry.k.assign("b"); entry.v.push_back(3); });
+    CHECK(StrTableHelper::BuildWith(str, options, err) == 2);
+    CHECK(err == "1 duplicate keys in Str, merged the values (mmdata.duplicate_key = MERGE)");
+    CHECK(str.table->find(mmdata::SHMString("b"))->second.size() == 2);
+    CHECK(str.table->find(mmdata::SHMString("a"))->second[0] == 2);
+    CHECK(str.table->find(mmdata::SHMString("c")) == str.table->end());
+
+    TestBuilder<Comp> comp;
+    comp.Add([](Comp& entry) { entry.a.assign("x"); entry.b = 1; entry.v = 1; });
+    comp.Add([](Comp& entry) { entry.a.assign("x"); entry.b = 2; entry.v = 2; });
+    CHECK(CompTableHelper::BuildWith(comp, options, err) == 2);
+    CHECK(err.empty());
+    CHECK(comp.table->find(Comp::key_type(mmdata::SHMString("x"), 2))->second == 2);
+    CHECK(comp.table->find(Comp::key_type(mmdata::SHMString("y"), 2)) == comp.table->end());
+
+    //a key the first build did not read has no position in the hash
+    TestBuilder<Comp> changed;
+    changed.Add([](Comp& entry) { entry.a.assign("x"); entry.b = 1; });
+    changed.Add([&changed](Comp& entry) { entry.a.assign("x"); entry.b = changed.builds == 1 ? 2 : 3; });
+    CHECK(CompTableHelper::BuildWith(changed, options, err) == -1);
+    CHECK(err == "1 entries dropped in Comp with a key out of the perfect hash");
+    CHECK(changed.table->size() == 1);
+
+    TestBuilder<Comp> none;
+    CHECK(CompTableHelper::BuildWith(none, options, err) == 0);
+    CHECK(none.table->find(Comp::key_type(mmdata::SHMString("x"), 1)) == none.table->end());
+
+    std::cout << "ok" << std::endl;
+    return 0;
+}
@@ -0,0 +1,53 @@
+// Reads lines like "timestamp 2020-02-29T00:00:00Z" or "duration -1.5s", and
+// prints the value parsed by FromString with ToString, or "invalid".
+#include <cstddef>
+#include <cstdint>
+#include <functional>
+#include <iostream>
+#include <string>
+
+//just enough of boost, rapidjson and kcfg to build the well-known types alone
+namespace boost
+{
+    template<typename T>
+    std::size_t hash_value(const T& v) { return std::hash<T>()(v); }
+}
+namespace rapidjson
+{
+    struct Value
+    {
+        typedef int AllocatorType;
+        bool IsObject() const { return false; }
+        bool HasMember(const char*) const { return false; }
+        const Value& operator[](const char*) const { return *this; }
+        bool IsInt64() const { return false; }
+        int64_t GetInt64() const { return 0; }
+        bool IsString() const { return false; }
+        const char* GetString() const { return ""; }
+    };
+}
+namespace kcfg
+{
+    inline void Serialize(rapidjson::Value&, rapidjson::Value::AllocatorType&, const char*, const std::string&) {}
+}
+
+#include "wkt.hpp"
+
+template<typename T>
+void Convert(const std::string& text)
+{
+    T v;
+    if(mmdata::pb::FromString(text.c_str(), v)) std::cout << mmdata::pb::ToString(v) << std::endl;
+    else std::cout << "invalid" << std::endl;
+}
+
+int main()
+{
+    std::string kind, text;
+    while(std::cin >> kind >> text)
+    {
+        if(kind == "timestamp") Convert<mmdata::pb::Timestamp>(text);
+        else Convert<mmdata::pb::Duration>(text);
+    }
+    return 0;
+}
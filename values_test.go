@@ -0,0 +1,34 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+//go:generate protoc -Itestdata -I. --include_imports --include_source_info --descriptor_set_out=testdata/values.pb testdata/values.proto
+//go:generate protoc -Itestdata -I. --include_imports --include_source_info --descriptor_set_out=testdata/values_errors.pb testdata/values_errors.proto
+
+func TestValues(t *testing.T) {
+	header := testGenerate(t, "values", "")["values.proto.hpp"]
+	for _, want := range []string{
+		//all the fields but the key, the oneof included
+		"value_type(const mmdata::SHMString& title, const mmdata::SHMVector<int32_t>::Type& tags, const Item& item, const OOneof& o):title(title),tags(tags),item(item),o(o)",
+		"value_type(const mmdata::CharAllocator& alloc):title(\"t\", alloc),tags(alloc),item(alloc),o(alloc)",
+		"value_type GetValue() const { return value_type(title, tags, item, o); }",
+		"typedef mmdata::SHMHashMap<int64_t, Row::value_type>::Type RowTableParent;",
+		//the fields with [(Value) = true] only
+		"value_type(const double& score, const int32_t& rank):score(score),rank(rank)",
+		"typedef mmdata::SHMMap<Multi::key_type, Multi::value_type>::Type MultiTableParent;",
+		"inline std::ostream& operator<<(std::ostream& os, const Multi::value_type& v)",
+		//a single value is used as it is
+		"typedef Item value_type;",
+		"const value_type& GetValue() const { return v; }",
+	} {
+		if !strings.Contains(header, want) {
+			t.Errorf("values.proto.hpp does not contain %q:\n%s", want, header)
+		}
+	}
+	if strings.Contains(header, "const int32_t& ignored") {
+		t.Errorf("values.proto.hpp has Multi.ignored in a value_type:\n%s", header)
+	}
+}
@@ -0,0 +1,362 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// wellKnownStructs maps the well-known types with a native layout to their
+// C++ types, declared in the header by DumpWellKnownTypes.
+var wellKnownStructs = map[string]string{
+	".google.protobuf.Timestamp": "mmdata::pb::Timestamp",
+	".google.protobuf.Duration":  "mmdata::pb::Duration",
+}
+
+// wrapperTypes are the well-known types wrapping a single value, mapped to
+// the type of their value with presence tracking.
+var wrapperTypes = map[string]bool{
+	".google.protobuf.DoubleValue": true,
+	".google.protobuf.FloatValue":  true,
+	".google.protobuf.Int64Value":  true,
+	".google.protobuf.UInt64Value": true,
+	".google.protobuf.Int32Value":  true,
+	".google.protobuf.UInt32Value": true,
+	".google.protobuf.BoolValue":   true,
+	".google.protobuf.StringValue": true,
+	".google.protobuf.BytesValue":  true,
+}
+
+// unsupportedTypes are the well-known types with no static layout.
+var unsupportedTypes = map[string]string{
+	".google.protobuf.Any":       "it holds a message of any type",
+	".google.protobuf.Struct":    "it holds dynamic json values",
+	".google.protobuf.Value":     "it holds dynamic json values",
+	".google.protobuf.ListValue": "it holds dynamic json values",
+}
+
+func isWellKnownType(name string) bool {
+	return strings.HasPrefix(name, ".google.protobuf.")
+}
+
+// isWellKnownStruct returns whether a field is a Timestamp or a Duration.
+func isWellKnownStruct(field *descriptor.FieldDescriptorProto) bool {
+	if field.GetType() != descriptor.FieldDescriptorProto_TYPE_MESSAGE {
+		return false
+	}
+	_, exist := wellKnownStructs[field.GetTypeName()]
+	return exist
+}
+
+// wrappedField returns the value field of a wrapper type field, or nil.
+func (g *Generator) wrappedField(field *descriptor.FieldDescriptorProto) *descriptor.FieldDescriptorProto {
+	if field.GetType() != descriptor.FieldDescriptorProto_TYPE_MESSAGE || !wrapperTypes[field.GetTypeName()] {
+		return nil
+	}
+	desc := g.getDesc(field.GetTypeName())
+	if nil == desc || len(desc.Field) != 1 {
+		return nil
+	}
+	return desc.Field[0]
+}
+
+// valueField returns the field holding the value of a field, the value field
+// of a wrapper or the field itself.
+func (g *Generator) valueField(field *descriptor.FieldDescriptorProto) *descriptor.FieldDescriptorProto {
+	if v := g.wrappedField(field); nil != v {
+		return v
+	}
+	return field
+}
+
+// verifyWellKnownTypes reports the fields of a message and its nested messages
+// using well-known types with no mapping.
+func (g *Generator) verifyWellKnownTypes(msg *descriptor.DescriptorProto) {
+	for _, nest := range msg.NestedType {
+		g.verifyWellKnownTypes(nest)
+	}
+	for _, field := range msg.Field {
+		name := field.GetTypeName()
+		if field.GetType() != descriptor.FieldDescriptorProto_TYPE_MESSAGE || !isWellKnownType(name) {
+			continue
+		}
+		if _, exist := wellKnownStructs[name]; exist {
+			continue
+		}
+		if wrapperTypes[name] {
+			if nil == g.wrappedField(field) {
+				g.fieldError(field, "Invalid wrapper type %s", name[1:])
+			}
+			continue
+		}
+		if reason, exist := unsupportedTypes[name]; exist {
+			g.fieldError(field, "Type %s is not supported, %s", name[1:], reason)
+		} else {
+			g.fieldError(field, "Type %s is not supported", name[1:])
+		}
+	}
+}
+
+// DumpWellKnownTypes emits the Timestamp and Duration types if the file uses
+// them. They are guarded since every header using them has them.
+func (g *Generator) DumpWellKnownTypes(file *descriptor.FileDescriptorProto) {
+	if fileHasField(file, isWellKnownStruct) {
+		fmt.Fprintf(&g.OutputBuffer, "%s\n", wellKnownTypesCode)
+	}
+}
+
+// wellKnownTypesCode holds google.protobuf.Timestamp and Duration as int64
+// nanoseconds, which covers the years 1678 to 2262. Their json form is the
+// proto3 one, like "1972-01-01T10:00:20.021Z" and "1.5s", and json integers
+// are parsed as nanoseconds too.
+const wellKnownTypesCode = `#ifndef MMDATA_WELL_KNOWN_TYPES_
+#define MMDATA_WELL_KNOWN_TYPES_
+#include <chrono>
+#include <cstdio>
+#include <ostream>
+#include <string>
+namespace mmdata
+{
+    namespace pb
+    {
+        //google.protobuf.Duration, a signed span of nanoseconds
+        struct Duration
+        {
+            int64_t nanos;
+            Duration():nanos(0)
+            {}
+            explicit Duration(int64_t ns):nanos(ns)
+            {}
+            template<typename Rep, typename Period>
+            Duration(const std::chrono::duration<Rep, Period>& d):nanos(std::chrono::duration_cast<std::chrono::nanoseconds>(d).count())
+            {}
+            std::chrono::nanoseconds ToChrono() const { return std::chrono::nanoseconds(nanos); }
+        };
+
+        //google.protobuf.Timestamp, nanoseconds since the unix epoch
+        struct Timestamp
+        {
+            int64_t nanos;
+            Timestamp():nanos(0)
+            {}
+            explicit Timestamp(int64_t ns):nanos(ns)
+            {}
+            template<typename D>
+            Timestamp(const std::chrono::time_point<std::chrono::system_clock, D>& tp):nanos(std::chrono::duration_cast<std::chrono::nanoseconds>(tp.time_since_epoch()).count())
+            {}
+            std::chrono::system_clock::time_point ToChrono() const
+            {
+                return std::chrono::system_clock::time_point(std::chrono::duration_cast<std::chrono::system_clock::duration>(std::chrono::nanoseconds(nanos)));
+            }
+            static Timestamp Now() { return Timestamp(std::chrono::system_clock::now()); }
+        };
+
+        inline bool operator==(const Duration& a, const Duration& b) { return a.nanos == b.nanos; }
+        inline bool operator!=(const Duration& a, const Duration& b) { return a.nanos != b.nanos; }
+        inline bool operator<(const Duration& a, const Duration& b) { return a.nanos < b.nanos; }
+        inline bool operator>(const Duration& a, const Duration& b) { return a.nanos > b.nanos; }
+        inline bool operator<=(const Duration& a, const Duration& b) { return a.nanos <= b.nanos; }
+        inline bool operator>=(const Duration& a, const Duration& b) { return a.nanos >= b.nanos; }
+        inline std::size_t hash_value(const Duration& v) { return boost::hash_value(v.nanos); }
+        inline bool operator==(const Timestamp& a, const Timestamp& b) { return a.nanos == b.nanos; }
+        inline bool operator!=(const Timestamp& a, const Timestamp& b) { return a.nanos != b.nanos; }
+        inline bool operator<(const Timestamp& a, const Timestamp& b) { return a.nanos < b.nanos; }
+        inline bool operator>(const Timestamp& a, const Timestamp& b) { return a.nanos > b.nanos; }
+        inline bool operator<=(const Timestamp& a, const Timestamp& b) { return a.nanos <= b.nanos; }
+        inline bool operator>=(const Timestamp& a, const Timestamp& b) { return a.nanos >= b.nanos; }
+        inline std::size_t hash_value(const Timestamp& v) { return boost::hash_value(v.nanos); }
+
+        //appends the fraction of a second with 0, 3, 6 or 9 digits
+        inline void AppendNanos(std::string& s, int64_t nanos)
+        {
+            if(nanos == 0) return;
+            char buf[16];
+            if(nanos % 1000000 == 0) snprintf(buf, sizeof(buf), ".%03d", static_cast<int>(nanos / 1000000));
+            else if(nanos % 1000 == 0) snprintf(buf, sizeof(buf), ".%06d", static_cast<int>(nanos / 1000));
+            else snprintf(buf, sizeof(buf), ".%09d", static_cast<int>(nanos));
+            s += buf;
+        }
+        inline bool ParseDigits(const char*& p, int n, int64_t& v)
+        {
+            v = 0;
+            for(int i = 0; i < n; i++, p++)
+            {
+                if(*p < '0' || *p > '9') return false;
+                v = v * 10 + (*p - '0');
+            }
+            return true;
+        }
+        //parses the fraction of a second after the dot, up to 9 digits
+        inline bool ParseNanos(const char*& p, int64_t& nanos)
+        {
+            nanos = 0;
+            if(*p != '.') return true;
+            p++;
+            int digits = 0;
+            for(; *p >= '0' && *p <= '9'; p++, digits++)
+            {
+                if(digits == 9) return false;
+                nanos = nanos * 10 + (*p - '0');
+            }
+            if(digits == 0) return false;
+            for(; digits < 9; digits++) nanos *= 10;
+            return true;
+        }
+
+        inline std::string ToString(const Duration& v)
+        {
+            uint64_t nanos = v.nanos < 0 ? -static_cast<uint64_t>(v.nanos) : static_cast<uint64_t>(v.nanos);
+            char buf[32];
+            snprintf(buf, sizeof(buf), "%s%llu", v.nanos < 0 ? "-" : "", static_cast<unsigned long long>(nanos / 1000000000));
+            std::string s(buf);
+            AppendNanos(s, static_cast<int64_t>(nanos % 1000000000));
+            s += "s";
+            return s;
+        }
+        inline bool FromString(const char* s, Duration& v)
+        {
+            const char* p = s;
+            bool negative = *p == '-';
+            if(negative) p++;
+            int64_t seconds = 0;
+            const char* start = p;
+            for(; *p >= '0' && *p <= '9'; p++)
+            {
+                if(seconds > 922337203LL) return false;
+                seconds = seconds * 10 + (*p - '0');
+            }
+            int64_t nanos = 0;
+            if(p == start || seconds > 9223372035LL || !ParseNanos(p, nanos) || p[0] != 's' || p[1] != 0) return false;
+            v.nanos = seconds * 1000000000 + nanos;
+            if(negative) v.nanos = -v.nanos;
+            return true;
+        }
+
+        inline std::string ToString(const Timestamp& v)
+        {
+            int64_t seconds = v.nanos / 1000000000;
+            int64_t nanos = v.nanos % 1000000000;
+            if(nanos < 0)
+            {
+                nanos += 1000000000;
+                seconds--;
+            }
+            int64_t days = seconds / 86400;
+            int64_t rem = seconds % 86400;
+            if(rem < 0)
+            {
+                rem += 86400;
+                days--;
+            }
+            //civil date of the days since 1970-01-01
+            days += 719468;
+            int64_t era = (days >= 0 ? days : days - 146096) / 146097;
+            int64_t doe = days - era * 146097;
+            int64_t yoe = (doe - doe / 1460 + doe / 36524 - doe / 146096) / 365;
+            int64_t doy = doe - (365 * yoe + yoe / 4 - yoe / 100);
+            int64_t mp = (5 * doy + 2) / 153;
+            int day = static_cast<int>(doy - (153 * mp + 2) / 5 + 1);
+            int month = static_cast<int>(mp < 10 ? mp + 3 : mp - 9);
+            int year = static_cast<int>(yoe + era * 400 + (month <= 2 ? 1 : 0));
+            char buf[64];
+            snprintf(buf, sizeof(buf), "%04d-%02d-%02dT%02d:%02d:%02d", year, month, day, static_cast<int>(rem / 3600), static_cast<int>(rem % 3600 / 60), static_cast<int>(rem % 60));
+            std::string s(buf);
+            AppendNanos(s, nanos);
+            s += "Z";
+            return s;
+        }
+        inline int64_t DaysInMonth(int64_t year, int64_t month)
+        {
+            static const int64_t days[] = {31, 28, 31, 30, 31, 30, 31, 31, 30, 31, 30, 31};
+            bool leap = year % 4 == 0 && (year % 100 != 0 || year % 400 == 0);
+            return month == 2 && leap ? 29 : days[month - 1];
+        }
+        inline bool FromString(const char* s, Timestamp& v)
+        {
+            const char* p = s;
+            int64_t year, month, day, hour, minute, second, nanos;
+            if(!ParseDigits(p, 4, year) || *p++ != '-' || !ParseDigits(p, 2, month) || *p++ != '-' || !ParseDigits(p, 2, day)) return false;
+            if(*p != 'T' && *p != 't') return false;
+            p++;
+            if(!ParseDigits(p, 2, hour) || *p++ != ':' || !ParseDigits(p, 2, minute) || *p++ != ':' || !ParseDigits(p, 2, second)) return false;
+            if(month < 1 || month > 12 || day < 1 || day > DaysInMonth(year, month) || hour > 23 || minute > 59 || second > 59) return false;
+            if(!ParseNanos(p, nanos)) return false;
+            int64_t offset = 0;
+            if(*p == 'Z' || *p == 'z')
+            {
+                p++;
+            }
+            else if(*p == '+' || *p == '-')
+            {
+                char sign = *p++;
+                int64_t offset_hour, offset_minute;
+                if(!ParseDigits(p, 2, offset_hour) || *p++ != ':' || !ParseDigits(p, 2, offset_minute)) return false;
+                offset = (offset_hour * 60 + offset_minute) * 60;
+                if(sign == '+') offset = -offset;
+            }
+            else
+            {
+                return false;
+            }
+            if(*p != 0) return false;
+            //days since 1970-01-01 of the civil date
+            year -= month <= 2 ? 1 : 0;
+            int64_t era = (year >= 0 ? year : year - 399) / 400;
+            int64_t yoe = year - era * 400;
+            int64_t doy = (153 * (month > 2 ? month - 3 : month + 9) + 2) / 5 + day - 1;
+            int64_t doe = yoe * 365 + yoe / 4 - yoe / 100 + doy;
+            int64_t days = era * 146097 + doe - 719468;
+            int64_t seconds = days * 86400 + hour * 3600 + minute * 60 + second + offset;
+            if(seconds < -9223372036LL || seconds > 9223372035LL) return false;
+            v.nanos = seconds * 1000000000 + nanos;
+            return true;
+        }
+
+        inline std::ostream& operator<<(std::ostream& os, const Duration& v)
+        {
+            return os << ToString(v);
+        }
+        inline std::ostream& operator<<(std::ostream& os, const Timestamp& v)
+        {
+            return os << ToString(v);
+        }
+    }
+}
+namespace kcfg
+{
+    template<typename T>
+    inline bool ParseWellKnownType(const rapidjson::Value& json, const char* name, T& v)
+    {
+        const rapidjson::Value* val = &json;
+        if(NULL != name && name[0] != 0)
+        {
+            if(!json.IsObject() || !json.HasMember(name)) return false;
+            val = &json[name];
+        }
+        if(val->IsInt64())
+        {
+            v.nanos = val->GetInt64();
+            return true;
+        }
+        return val->IsString() && mmdata::pb::FromString(val->GetString(), v);
+    }
+    inline bool Parse(const rapidjson::Value& json, const char* name, mmdata::pb::Duration& v)
+    {
+        return ParseWellKnownType(json, name, v);
+    }
+    inline bool Parse(const rapidjson::Value& json, const char* name, mmdata::pb::Timestamp& v)
+    {
+        return ParseWellKnownType(json, name, v);
+    }
+    inline void Serialize(rapidjson::Value& json, rapidjson::Value::AllocatorType& allocator, const char* name, const mmdata::pb::Duration& v)
+    {
+        Serialize(json, allocator, name, mmdata::pb::ToString(v));
+    }
+    inline void Serialize(rapidjson::Value& json, rapidjson::Value::AllocatorType& allocator, const char* name, const mmdata::pb::Timestamp& v)
+    {
+        Serialize(json, allocator, name, mmdata::pb::ToString(v));
+    }
+}
+#endif /* MMDATA_WELL_KNOWN_TYPES_ */
+`
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+//go:generate protoc -Itestdata -I. --include_imports --include_source_info --descriptor_set_out=testdata/wkt.pb testdata/wkt.proto
+//go:generate protoc -Itestdata -I. --include_imports --include_source_info --descriptor_set_out=testdata/wkt_errors.pb testdata/wkt_errors.proto
+
+func TestWellKnownTypeMappings(t *testing.T) {
+	header := testGenerate(t, "wkt", "")["wkt.proto.hpp"]
+	tests := []struct {
+		field string
+		want  string
+	}{
+		{"created", "mmdata::pb::Timestamp created;"},
+		{"ttl", "mmdata::pb::Duration ttl;"},
+		{"times", "mmdata::SHMVector<mmdata::pb::Timestamp>::Type times;"},
+		{"d", "void set_d(const double& v) { this->d = v; set_has_d(); }"},
+		{"f", "void set_f(const float& v) { this->f = v; set_has_f(); }"},
+		{"i64", "void set_i64(const int64_t& v) { this->i64 = v; set_has_i64(); }"},
+		{"u64", "void set_u64(const uint64_t& v) { this->u64 = v; set_has_u64(); }"},
+		{"i32", "void set_i32(const int32_t& v) { this->i32 = v; set_has_i32(); }"},
+		{"u32", "void set_u32(const uint32_t& v) { this->u32 = v; set_has_u32(); }"},
+		{"b", "void set_b(const bool& v) { this->b = v; set_has_b(); }"},
+		{"s", "void set_s(const mmdata::SHMString& v) { this->s = v; set_has_s(); }"},
+		{"bs", "void set_bs(const mmdata::pb::Bytes& v) { this->bs = v; set_has_bs(); }"},
+		{"i32", "if(Parse(*val, \"i32\", v.i32)) v.set_has_i32();"},
+		{"k", "typedef mmdata::pb::Timestamp key_type;"},
+	}
+	for _, test := range tests {
+		if !strings.Contains(header, test.want) {
+			t.Errorf("wkt.proto.hpp does not contain %q for %s:\n%s", test.want, test.field, header)
+		}
+	}
+	if strings.Contains(header, "google::") {
+		t.Errorf("wkt.proto.hpp refers to the google types:\n%s", header)
+	}
+}
+
+func TestWellKnownTypesFromString(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"timestamp 1972-01-01T10:00:20.021Z", "1972-01-01T10:00:20.021Z"},
+		{"timestamp 1969-12-31T23:59:59.5Z", "1969-12-31T23:59:59.500Z"},
+		{"timestamp 2021-01-01T08:00:00+08:00", "2021-01-01T00:00:00Z"},
+		{"timestamp 2021-01-01T00:00:00.123456789Z", "2021-01-01T00:00:00.123456789Z"},
+		//leap days
+		{"timestamp 2020-02-29T00:00:00Z", "2020-02-29T00:00:00Z"},
+		{"timestamp 2000-02-29T00:00:00Z", "2000-02-29T00:00:00Z"},
+		{"timestamp 2019-02-29T00:00:00Z", "invalid"},
+		{"timestamp 1900-02-29T00:00:00Z", "invalid"},
+		{"timestamp 2020-02-30T00:00:00Z", "invalid"},
+		//day 31 of the 30-day months
+		{"timestamp 2021-01-31T00:00:00Z", "2021-01-31T00:00:00Z"},
+		{"timestamp 2021-04-31T00:00:00Z", "invalid"},
+		{"timestamp 2021-06-31T00:00:00Z", "invalid"},
+		{"timestamp 2021-09-31T00:00:00Z", "invalid"},
+		{"timestamp 2021-11-31T00:00:00Z", "invalid"},
+		{"timestamp 2021-12-31T23:59:59Z", "2021-12-31T23:59:59Z"},
+		{"timestamp 2021-13-01T00:00:00Z", "invalid"},
+		{"timestamp 2021-01-01T24:00:00Z", "invalid"},
+		//nanos overflow, in the fraction and out of the int64 range
+		{"timestamp 2021-01-01T00:00:00.1234567891Z", "invalid"},
+		{"timestamp 2021-01-01T00:00:00.Z", "invalid"},
+		{"timestamp 2262-04-11T23:47:15Z", "2262-04-11T23:47:15Z"},
+		{"timestamp 2263-01-01T00:00:00Z", "invalid"},
+		{"timestamp 1677-09-21T00:12:44Z", "1677-09-21T00:12:44Z"},
+		{"timestamp 1677-01-01T00:00:00Z", "invalid"},
+		{"duration 1.5s", "1.500s"},
+		{"duration 0s", "0s"},
+		{"duration 0.000001s", "0.000001s"},
+		{"duration 9223372035.999999999s", "9223372035.999999999s"},
+		{"duration 9223372036s", "invalid"},
+		{"duration 99999999999999999999s", "invalid"},
+		{"duration 1.0000000001s", "invalid"},
+		{"duration 1.s", "invalid"},
+		{"duration s", "invalid"},
+		{"duration 1", "invalid"},
+		//negative durations
+		{"duration -1.5s", "-1.500s"},
+		{"duration -0.000000001s", "-0.000000001s"},
+		{"duration -0s", "0s"},
+		{"duration -9223372035.999999999s", "-9223372035.999999999s"},
+		{"duration --1s", "invalid"},
+	}
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "wkt.hpp"), []byte(wellKnownTypesCode), 0644); err != nil {
+		t.Fatal(err)
+	}
+	exe := compileCpp(t, dir, "wkt")
+	var input []string
+	for _, test := range tests {
+		input = append(input, test.input)
+	}
+	got := runCpp(t, exe, input)
+	if len(got) != len(tests) {
+		t.Fatalf("wkt printed %d lines for %d inputs:\n%s", len(got), len(tests), strings.Join(got, "\n"))
+	}
+	for i, test := range tests {
+		if got[i] != test.want {
+			t.Errorf("FromString(%q) = %s, want %s", test.input, got[i], test.want)
+		}
+	}
+}